@@ -4,45 +4,384 @@ import (
 	"clean-arch/internal/app/config"
 	"clean-arch/internal/app/controllers"
 	"clean-arch/internal/app/utils"
+	"clean-arch/internal/breach"
 	"clean-arch/internal/core/database"
+	"clean-arch/internal/core/models"
 	"clean-arch/internal/core/repository"
 	"clean-arch/internal/core/services"
+	"clean-arch/internal/health"
+	"clean-arch/internal/lifecycle"
 	"clean-arch/internal/logger"
-	"fmt"
+	"clean-arch/internal/mailer"
+	"clean-arch/internal/metrics"
+	"clean-arch/internal/secrets"
+	"clean-arch/internal/storage"
+	"clean-arch/internal/tracing"
+	"clean-arch/internal/validation"
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func main() {
-	Gin := gin.Default()
-
 	log := logger.NewLogrusLogger()
 
-	configEnv := config.ConfigEnv()
-	fmt.Println("config env", configEnv)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], log); err != nil {
+			log.Error("Migration failed", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 
-	db := database.ConnectDatabase(*configEnv)
-	if db == nil {
-		log.Error("Failed to connect to database")
+	configEnv, err := config.Load(os.Args[1:])
+	if config.IsHelp(err) {
 		return
 	}
+	if err != nil {
+		log.Error("Failed to load config", err.Error())
+		os.Exit(1)
+	}
+	if err := log.SetLevel(configEnv.LOGLEVEL); err != nil {
+		log.Error("Invalid log level", err)
+	}
+	log.Info("Loaded config", configEnv.Redacted())
+	services.BcryptCost = configEnv.BCRYPTCOST
+
+	policy, err := passwordPolicy(configEnv)
+	if err != nil {
+		log.Error("Failed to set up the password policy", err.Error())
+		os.Exit(1)
+	}
+	validation.Default.SetPasswordPolicy(policy)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName:  configEnv.TRACINGSERVICENAME,
+		Exporter:     configEnv.TRACINGEXPORTER,
+		OTLPEndpoint: configEnv.TRACINGOTLPENDPOINT,
+		OTLPInsecure: configEnv.TRACINGOTLPINSECURE,
+		File:         configEnv.TRACINGFILE,
+		SampleRatio:  configEnv.TRACINGSAMPLERATIO,
+	})
+	if err != nil {
+		log.Error("Failed to set up tracing", err.Error())
+		os.Exit(1)
+	}
+
+	// Hooks stop in reverse order: the server drains first, then the
+	// background workers, then the database pool, and the spans recorded
+	// along the way are flushed last.
+	app := lifecycle.New(log)
+	app.Append(lifecycle.Hook{
+		Name:   "tracing",
+		OnStop: shutdownTracing,
+	})
+
+	// fail stops whatever was set up so far and exits; every failure from
+	// here until the app runs goes through it.
+	fail := func(message string, err error) {
+		log.Error(message, err.Error())
+		ctx, cancel := context.WithTimeout(context.Background(), configEnv.SHUTDOWNTIMEOUT)
+		if err := app.Abort(ctx); err != nil {
+			log.Error("Shutdown failed", err.Error())
+		}
+		cancel()
+		os.Exit(1)
+	}
+
+	// The tracing and request logging middlewares run first so that they
+	// also cover recovered panics, and the request log carries the trace ID.
+	Gin := gin.New()
+	if err := Gin.SetTrustedProxies(configEnv.TrustedProxies()); err != nil {
+		fail("Failed to set trusted proxies", err)
+	}
+	Gin.Use(tracing.Middleware(), utils.RequestLogger(log), gin.Recovery(), metrics.Middleware(), utils.ErrorHandler())
+
+	db, err := database.ConnectDatabase(*configEnv, log)
+	if err != nil {
+		fail("Failed to connect to database", err)
+	}
+	app.Append(lifecycle.Hook{
+		Name:   "database",
+		OnStop: func(context.Context) error { return database.Close(db) },
+	})
+
+	replicaDB, err := database.ConnectReplica(*configEnv, log)
+	if err != nil {
+		fail("Failed to connect to read replica", err)
+	}
+	if replicaDB != nil {
+		app.Append(lifecycle.Hook{
+			Name:   "read replica",
+			OnStop: func(context.Context) error { return database.Close(replicaDB) },
+		})
+	}
+
+	if err := instrumentDatabase(db, "primary"); err != nil {
+		fail("Failed to instrument database", err)
+	}
+	if replicaDB != nil {
+		if err := instrumentDatabase(replicaDB, "replica"); err != nil {
+			fail("Failed to instrument database", err)
+		}
+	}
+
+	if configEnv.MIGRATEONSTART {
+		migrator, err := newMigrator(db)
+		if err == nil {
+			err = migrateUp(context.Background(), migrator, log)
+		}
+		if err != nil {
+			fail("Failed to migrate database", err)
+		}
+	}
+
+	healthChecks := health.New(configEnv.HEALTHCHECKTIMEOUT)
+	healthChecks.Register("database", func(ctx context.Context) error { return database.Ping(ctx, db) })
+	if replicaDB != nil {
+		healthChecks.Register("database_replica", func(ctx context.Context) error { return database.Ping(ctx, replicaDB) })
+	}
 
 	userRepo := repository.NewUserRepository(db)
-	userService := services.NewUserService(userRepo)
+	userRepo.Replica = replicaDB
+	pendingUserRepo := repository.NewPendingUserRepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	revocationStore := repository.NewRevocationRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	loginAttemptStore := repository.NewLoginAttemptRepository(db)
+
+	var mail mailer.Mailer
+	if configEnv.SMTPHOST != "" {
+		mail = mailer.NewSMTPMailer(configEnv.SMTPHOST, configEnv.SMTPPORT, configEnv.SMTPUSER, configEnv.SMTPPASSWORD, configEnv.MAILFROM)
+	} else {
+		mail = mailer.NewFileMailer(configEnv.MAILDIR, configEnv.MAILFROM)
+	}
 
-	tokenGenerator := &utils.RealTokenGenerator{}
+	mfaBox, err := secrets.NewBox([]byte(configEnv.MFAENCRYPTIONKEY))
+	if err != nil {
+		fail("Failed to set up MFA secret encryption", err)
+	}
+
+	blobStore := storage.NewLocalBlobStore(configEnv.UPLOADDIR, "/uploads")
+	Gin.Static("/uploads", configEnv.UPLOADDIR)
+
+	userService := services.NewUserService(userRepo, pendingUserRepo, mail, blobStore, services.UserServiceConfig{
+		Verification: services.VerificationConfig{
+			Secret:  []byte(configEnv.VERIFICATIONSECRET),
+			LinkURL: configEnv.VERIFICATIONLINKURL,
+		},
+		DeletionGracePeriod:    configEnv.DELETIONGRACEPERIOD,
+		PurgeMode:              configEnv.PURGEMODE,
+		ConcealSignupConflicts: configEnv.SIGNUPCONCEALCONFLICTS,
+	})
+	tokenService := services.NewTokenService(refreshTokenRepo, revocationStore, userRepo, configEnv.ACCESSTOKENTTL, configEnv.REFRESHTOKENTTL)
+	loginGuard := services.NewLoginGuard(loginAttemptStore, services.LoginGuardConfig{
+		MaxFailures:   configEnv.LOGINMAXFAILURES,
+		IPMaxFailures: configEnv.LOGINIPMAXFAILURES,
+		LockoutWindow: configEnv.LOGINLOCKOUTWINDOW,
+	})
+	adminService := services.NewAdminService(userRepo, roleRepo, tokenService, loginGuard)
+	roleService := services.NewRoleService(roleRepo, userRepo, tokenService)
+	mfaService := services.NewMFAService(userRepo, recoveryCodeRepo, mfaBox)
+	passwordService := services.NewPasswordService(userRepo, passwordResetRepo, passwordHistoryRepo, tokenService, mail, configEnv.PASSWORDRESETLINKURL)
+	app.Append(lifecycle.Hook{
+		Name:   "password reset emails",
+		OnStop: passwordService.Wait,
+	})
+
+	keyRing, err := utils.BuildKeyRing(utils.KeyRingConfig{
+		Algorithm:        configEnv.JWTALGORITHM,
+		KeyID:            configEnv.JWTKEYID,
+		KeyFile:          configEnv.JWTKEYFILE,
+		Secret:           configEnv.JWTSECRET,
+		VerificationKeys: configEnv.JWTVERIFICATIONKEYS,
+		LegacySecret:     configEnv.JWTLEGACYSECRET,
+	})
+	if err != nil {
+		fail("Failed to load JWT keys", err)
+	}
+
+	tokenGenerator := &utils.RealTokenGenerator{
+		AccessTTL:   configEnv.ACCESSTOKENTTL,
+		Revocations: revocationStore,
+		Keys:        keyRing,
+	}
 
-	userController := controllers.NewUserController(userService, tokenGenerator)
+	app.AppendTicker("token purge", 10*time.Minute, func() {
+		if err := tokenService.PurgeExpired(context.Background()); err != nil {
+			log.Error("Failed to purge expired tokens", err)
+		}
+		if err := loginGuard.PurgeExpired(); err != nil {
+			log.Error("Failed to purge login attempts", err)
+		}
+		if _, err := userService.PurgeExpiredRegistrations(context.Background()); err != nil {
+			log.Error("Failed to purge expired registrations", err)
+		}
+	})
+
+	app.AppendTicker("account purge", time.Hour, func() {
+		purged, err := userService.PurgeDeletedAccounts(context.Background())
+		if err != nil {
+			log.Error("Failed to purge deleted accounts", err)
+		} else if purged > 0 {
+			log.Info("Purged deleted accounts", purged)
+		}
+	})
+
+	userController := controllers.NewUserController(userService, tokenService, roleService, mfaService, loginGuard, tokenGenerator)
+	passwordController := controllers.NewPasswordController(passwordService)
+	adminController := controllers.NewAdminController(adminService)
+	roleController := controllers.NewRoleController(roleService)
+	mfaController := controllers.NewMFAController(mfaService)
+	healthController := controllers.NewHealthController(healthChecks)
+
+	if err := roleService.SeedDefaults(context.Background()); err != nil {
+		fail("Failed to seed roles and permissions", err)
+	}
+
+	if configEnv.ADMINEMAIL != "" {
+		created, err := adminService.BootstrapAdmin(context.Background(), configEnv.ADMINEMAIL, configEnv.ADMINPASSWORD)
+		if err != nil {
+			fail("Failed to bootstrap admin account", err)
+		}
+		if created {
+			log.Info("Bootstrapped admin account", configEnv.ADMINEMAIL)
+		}
+	}
+
+	rateLimitStore := utils.NewInMemoryRateLimitStore()
+	// The specs were checked when the config was loaded.
+	rateLimit := func(name, spec string, key utils.RateLimitKeyFunc) gin.HandlerFunc {
+		policy, _ := utils.ParseRateLimit(name, spec, key)
+		return utils.RateLimit(rateLimitStore, policy)
+	}
+	// Endpoints that send email share one bucket per IP, as do the ones that
+	// check credentials or tokens. Token refresh counts against a bucket of its
+	// own, so a client renewing its session does not use up its logins.
+	emailLimit := rateLimit("email", configEnv.RATELIMITEMAIL, utils.KeyByIP)
+	loginLimit := rateLimit("login", configEnv.RATELIMITLOGIN, utils.KeyByIP)
+	refreshLimit := rateLimit("login", configEnv.RATELIMITLOGIN, utils.KeyByRoute)
+	userLimit := rateLimit("user", configEnv.RATELIMITUSER, utils.KeyByUser)
+
+	Gin.GET("/metrics", metrics.Handler())
+	Gin.GET("/healthz", healthController.Liveness)
+	Gin.GET("/readyz", healthController.Readiness)
+	Gin.GET("/.well-known/jwks.json", utils.JWKSHandler(keyRing))
 
 	api := Gin.Group("/api/v1/users")
 	{
-		api.POST("/signup", userController.SignUp)
-		api.POST("/login", userController.Login)
-		api.GET("/profile", utils.AuthMiddleware("user", tokenGenerator), userController.GetProfile)
+		api.POST("/signup", emailLimit, userController.SignUp)
+		api.POST("/verify-email", loginLimit, userController.VerifyEmail)
+		api.POST("/resend-verification", emailLimit, userController.ResendVerification)
+		api.POST("/login", loginLimit, userController.Login)
+		api.POST("/login/mfa", loginLimit, userController.LoginMFA)
+		api.POST("/token/refresh", refreshLimit, userController.RefreshToken)
+		api.POST("/forgot-password", emailLimit, passwordController.ForgotPassword)
+		api.POST("/reset-password", loginLimit, passwordController.ResetPassword)
+		api.GET("/profile", utils.AuthMiddleware("user", tokenGenerator), userLimit, userController.GetProfile)
+		api.PATCH("/profile", utils.AuthMiddleware("user", tokenGenerator), userLimit, userController.UpdateProfile)
+		api.POST("/profile/picture", utils.AuthMiddleware("user", tokenGenerator), userLimit, userController.UploadProfilePicture)
+		api.POST("/logout", utils.AuthMiddleware("user", tokenGenerator), userLimit, userController.Logout)
+		api.POST("/logout/all", utils.AuthMiddleware("user", tokenGenerator), userLimit, userController.LogoutAll)
+		api.PUT("/password", utils.AuthMiddleware("user", tokenGenerator), userLimit, passwordController.ChangePassword)
+		api.DELETE("/me", utils.AuthMiddleware("user", tokenGenerator), userLimit, userController.DeleteAccount)
+		api.POST("/mfa/enroll", utils.AuthMiddleware("user", tokenGenerator), userLimit, mfaController.BeginEnrollment)
+		api.POST("/mfa/enroll/confirm", utils.AuthMiddleware("user", tokenGenerator), userLimit, mfaController.ConfirmEnrollment)
+		api.POST("/mfa/disable", utils.AuthMiddleware("user", tokenGenerator), userLimit, mfaController.Disable)
 	}
 
-	err := Gin.Run(":3000")
-	if err != nil {
-		log.Error("Failed to start server", err)
+	admin := Gin.Group("/api/v1/admin", utils.AuthMiddleware("", tokenGenerator), userLimit)
+	{
+		admin.GET("/users", utils.RequirePermission(models.PermUsersRead), adminController.ListUsers)
+		admin.GET("/users/:id", utils.RequirePermission(models.PermUsersRead), adminController.GetUser)
+		admin.POST("/users/:id/block", utils.RequirePermission(models.PermUsersBlock), adminController.BlockUser)
+		admin.POST("/users/:id/unblock", utils.RequirePermission(models.PermUsersBlock), adminController.UnblockUser)
+		admin.POST("/users/:id/unlock", utils.RequirePermission(models.PermUsersBlock), adminController.UnlockUser)
+		admin.POST("/users/:id/logout", utils.RequirePermission(models.PermUsersLogout), adminController.ForceLogout)
+		admin.POST("/users/:id/roles", utils.RequirePermission(models.PermRolesManage), roleController.AssignRole)
+		admin.DELETE("/users/:id/roles/:role", utils.RequirePermission(models.PermRolesManage), roleController.RevokeRole)
+
+		admin.GET("/roles", utils.RequirePermission(models.PermRolesManage), roleController.ListRoles)
+		admin.POST("/roles", utils.RequirePermission(models.PermRolesManage), roleController.CreateRole)
+		admin.PUT("/roles/:name/permissions", utils.RequirePermission(models.PermRolesManage), roleController.SetRolePermissions)
+		admin.DELETE("/roles/:name", utils.RequirePermission(models.PermRolesManage), roleController.DeleteRole)
+		admin.GET("/permissions", utils.RequirePermission(models.PermRolesManage), roleController.ListPermissions)
+	}
+
+	app.AppendServer("http server", &http.Server{
+		Addr:              net.JoinHostPort(configEnv.SERVERHOST, strconv.Itoa(configEnv.SERVERPORT)),
+		Handler:           Gin,
+		ReadTimeout:       configEnv.SERVERREADTIMEOUT,
+		ReadHeaderTimeout: configEnv.SERVERREADHEADERTIMEOUT,
+		WriteTimeout:      configEnv.SERVERWRITETIMEOUT,
+		IdleTimeout:       configEnv.SERVERIDLETIMEOUT,
+		MaxHeaderBytes:    configEnv.SERVERMAXHEADERBYTES,
+	})
+
+	// Stopped before the server: report not ready and give load balancers
+	// shutdown_delay to notice before connections are refused.
+	app.Append(lifecycle.Hook{
+		Name: "readiness",
+		OnStop: func(ctx context.Context) error {
+			healthChecks.ShutDown()
+			select {
+			case <-time.After(configEnv.SHUTDOWNDELAY):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := app.Run(ctx, configEnv.SHUTDOWNTIMEOUT); err != nil {
+		log.Error("Server stopped with errors", err.Error())
+		os.Exit(1)
+	}
+	log.Info("Server stopped")
+}
+
+// instrumentDatabase registers the metrics and tracing callbacks on a
+// connection pool.
+func instrumentDatabase(db *gorm.DB, name string) error {
+	if err := db.Use(&metrics.GormPlugin{Database: name}); err != nil {
+		return err
+	}
+	return db.Use(&tracing.GormPlugin{Database: name})
+}
+
+// passwordPolicy builds the rules for new passwords from the configuration,
+// loading the breached password list if one is set.
+func passwordPolicy(configEnv *config.Env) (*models.PasswordPolicy, error) {
+	policy := &models.PasswordPolicy{
+		MinLength:      configEnv.PASSWORDMINLENGTH,
+		MaxLength:      configEnv.PASSWORDMAXLENGTH,
+		RequireUpper:   configEnv.PASSWORDREQUIREUPPER,
+		RequireLower:   configEnv.PASSWORDREQUIRELOWER,
+		RequireDigit:   configEnv.PASSWORDREQUIREDIGIT,
+		RequireSymbol:  configEnv.PASSWORDREQUIRESYMBOL,
+		RejectIdentity: configEnv.PASSWORDREJECTIDENTITY,
+		MinStrength:    configEnv.PASSWORDMINSTRENGTH,
+	}
+	if configEnv.PASSWORDBREACHEDLIST != "" {
+		breached, err := breach.Load(configEnv.PASSWORDBREACHEDLIST)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = breached
 	}
+	return policy, nil
 }
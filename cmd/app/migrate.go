@@ -0,0 +1,119 @@
+package main
+
+import (
+	"clean-arch/internal/app/config"
+	"clean-arch/internal/core/database"
+	"clean-arch/internal/logger"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const migrateUsage = "usage: app migrate up | down [steps] | status | create <name> [flags]"
+
+// runMigrate implements the migrate subcommand. Flags after the command are
+// the same as for the server.
+func runMigrate(args []string, log logger.Logger) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	command, args := args[0], args[1:]
+	var arg string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		arg, args = args[0], args[1:]
+	}
+
+	if command == "create" {
+		if arg == "" {
+			return errors.New(migrateUsage)
+		}
+		paths, err := database.CreateMigration(database.MigrationsDir, arg)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			log.WithFields(logger.Fields{"path": path}).Info("Created migration file")
+		}
+		return nil
+	}
+
+	configEnv, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+	db, err := database.ConnectDatabase(*configEnv, log)
+	if err != nil {
+		return err
+	}
+	defer database.Close(db)
+
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		return migrateUp(ctx, migrator, log)
+	case "down":
+		steps := 1
+		if arg != "" {
+			if steps, err = strconv.Atoi(arg); err != nil || steps < 1 {
+				return errors.New("steps must be a positive number")
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		for _, migration := range rolledBack {
+			log.Info("Rolled back migration", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Modified {
+				appliedAt += " (modified)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+func newMigrator(db *gorm.DB) (*database.Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := database.Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return database.NewMigrator(sqlDB, migrations), nil
+}
+
+func migrateUp(ctx context.Context, migrator *database.Migrator, log logger.Logger) error {
+	applied, err := migrator.Up(ctx)
+	for _, migration := range applied {
+		log.Info("Applied migration", migration.Version, migration.Name)
+	}
+	return err
+}
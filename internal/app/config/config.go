@@ -1,37 +1,481 @@
 package config
 
 import (
+	"clean-arch/internal/app/utils"
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/tracing"
+	"errors"
 	"fmt"
-	"log"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// EnvPrefix is prepended to every key to form its environment variable, e.g.
+// db_password is read from USERAPI_DB_PASSWORD.
+const EnvPrefix = "USERAPI"
+
+// Env is the application configuration. Each field is read from the key in
+// its mapstructure tag; fields tagged secret are redacted when printed and
+// cannot be set on the command line.
 type Env struct {
-	DBUSER     string
-	DBPASSWORD string
-	DBPORT     string
-	DBHOST     string
-	DBNAME     string
-	SSLMODE string
+	SERVERHOST              string        `mapstructure:"server_host"`
+	SERVERPORT              int           `mapstructure:"server_port"`
+	SERVERREADTIMEOUT       time.Duration `mapstructure:"server_read_timeout"`
+	SERVERREADHEADERTIMEOUT time.Duration `mapstructure:"server_read_header_timeout"`
+	SERVERWRITETIMEOUT      time.Duration `mapstructure:"server_write_timeout"`
+	SERVERIDLETIMEOUT       time.Duration `mapstructure:"server_idle_timeout"`
+	SERVERMAXHEADERBYTES    int           `mapstructure:"server_max_header_bytes"`
+	SHUTDOWNTIMEOUT         time.Duration `mapstructure:"shutdown_timeout"`
+	SHUTDOWNDELAY           time.Duration `mapstructure:"shutdown_delay"`
+	HEALTHCHECKTIMEOUT      time.Duration `mapstructure:"health_check_timeout"`
+	LOGLEVEL                string        `mapstructure:"log_level"`
+
+	DBUSER     string `mapstructure:"db_user"`
+	DBPASSWORD string `mapstructure:"db_password" secret:"true"`
+	DBPORT     string `mapstructure:"db_port"`
+	DBHOST     string `mapstructure:"db_host"`
+	DBNAME     string `mapstructure:"db_name"`
+	SSLMODE    string `mapstructure:"db_sslmode"`
+
+	DBTIMEZONE         string        `mapstructure:"db_timezone"`
+	DBSTATEMENTTIMEOUT time.Duration `mapstructure:"db_statement_timeout"`
+	DBMAXOPENCONNS     int           `mapstructure:"db_max_open_conns"`
+	DBMAXIDLECONNS     int           `mapstructure:"db_max_idle_conns"`
+	DBCONNMAXLIFETIME  time.Duration `mapstructure:"db_conn_max_lifetime"`
+	DBCONNECTATTEMPTS  int           `mapstructure:"db_connect_attempts"`
+	DBCONNECTBACKOFF   time.Duration `mapstructure:"db_connect_backoff"`
+	// DBSLOWQUERYTHRESHOLD logs queries that take longer as warnings; zero
+	// turns the warnings off.
+	DBSLOWQUERYTHRESHOLD time.Duration `mapstructure:"db_slow_query_threshold"`
+	// DBREPLICAHOST is an optional read replica as host[:port]; it shares
+	// the primary's credentials.
+	DBREPLICAHOST string `mapstructure:"db_replica_host"`
+
+	MIGRATEONSTART bool `mapstructure:"migrate_on_start"`
+
+	SMTPHOST     string `mapstructure:"smtp_host"`
+	SMTPPORT     string `mapstructure:"smtp_port"`
+	SMTPUSER     string `mapstructure:"smtp_user"`
+	SMTPPASSWORD string `mapstructure:"smtp_password" secret:"true"`
+	MAILFROM     string `mapstructure:"mail_from"`
+	MAILDIR      string `mapstructure:"mail_dir"`
+
+	VERIFICATIONSECRET   string `mapstructure:"verification_secret" secret:"true"`
+	VERIFICATIONLINKURL  string `mapstructure:"verification_link_url"`
+	PASSWORDRESETLINKURL string `mapstructure:"password_reset_link_url"`
+	MFAENCRYPTIONKEY     string `mapstructure:"mfa_encryption_key" secret:"true"`
+	BCRYPTCOST           int    `mapstructure:"bcrypt_cost"`
+
+	PASSWORDMINLENGTH      int  `mapstructure:"password_min_length"`
+	PASSWORDMAXLENGTH      int  `mapstructure:"password_max_length"`
+	PASSWORDREQUIREUPPER   bool `mapstructure:"password_require_upper"`
+	PASSWORDREQUIRELOWER   bool `mapstructure:"password_require_lower"`
+	PASSWORDREQUIREDIGIT   bool `mapstructure:"password_require_digit"`
+	PASSWORDREQUIRESYMBOL  bool `mapstructure:"password_require_symbol"`
+	PASSWORDREJECTIDENTITY bool `mapstructure:"password_reject_identity"`
+	// PASSWORDMINSTRENGTH is the lowest strength score accepted, from 0 (no
+	// check) to 4.
+	PASSWORDMINSTRENGTH int `mapstructure:"password_min_strength"`
+	// PASSWORDBREACHEDLIST is a file of SHA-1 hashes of breached passwords,
+	// one per line, that users may not choose.
+	PASSWORDBREACHEDLIST string `mapstructure:"password_breached_list"`
+
+	UPLOADDIR string `mapstructure:"upload_dir"`
+
+	DELETIONGRACEPERIOD time.Duration `mapstructure:"deletion_grace_period"`
+	PURGEMODE           string        `mapstructure:"purge_mode"`
+
+	// SIGNUPCONCEALCONFLICTS answers signups for registered emails like
+	// successful ones and tells the owner by email instead.
+	SIGNUPCONCEALCONFLICTS bool `mapstructure:"signup_conceal_conflicts"`
+
+	ADMINEMAIL    string `mapstructure:"admin_email"`
+	ADMINPASSWORD string `mapstructure:"admin_password" secret:"true"`
+
+	LOGINMAXFAILURES   int           `mapstructure:"login_max_failures"`
+	LOGINIPMAXFAILURES int           `mapstructure:"login_ip_max_failures"`
+	LOGINLOCKOUTWINDOW time.Duration `mapstructure:"login_lockout_window"`
+
+	// TRUSTEDPROXIES lists, comma-separated, the IPs or CIDRs of the proxies
+	// whose X-Forwarded-For header is believed. By default none is, and the
+	// client IP is the address of the connection.
+	TRUSTEDPROXIES string `mapstructure:"trusted_proxies"`
+
+	RATELIMITEMAIL string `mapstructure:"rate_limit_email"`
+	RATELIMITLOGIN string `mapstructure:"rate_limit_login"`
+	RATELIMITUSER  string `mapstructure:"rate_limit_user"`
+
+	ACCESSTOKENTTL  time.Duration `mapstructure:"access_token_ttl"`
+	REFRESHTOKENTTL time.Duration `mapstructure:"refresh_token_ttl"`
+
+	JWTALGORITHM        string `mapstructure:"jwt_algorithm"`
+	JWTKEYID            string `mapstructure:"jwt_key_id"`
+	JWTKEYFILE          string `mapstructure:"jwt_key_file"`
+	JWTSECRET           string `mapstructure:"jwt_secret" secret:"true"`
+	JWTVERIFICATIONKEYS string `mapstructure:"jwt_verification_keys"`
+	JWTLEGACYSECRET     string `mapstructure:"jwt_legacy_secret" secret:"true"`
+
+	// TRACINGEXPORTER is none, stdout, file or otlp.
+	TRACINGEXPORTER     string  `mapstructure:"tracing_exporter"`
+	TRACINGSERVICENAME  string  `mapstructure:"tracing_service_name"`
+	TRACINGOTLPENDPOINT string  `mapstructure:"tracing_otlp_endpoint"`
+	TRACINGOTLPINSECURE bool    `mapstructure:"tracing_otlp_insecure"`
+	TRACINGFILE         string  `mapstructure:"tracing_file"`
+	TRACINGSAMPLERATIO  float64 `mapstructure:"tracing_sample_ratio"`
+}
+
+var defaults = map[string]interface{}{
+	"server_port":                3000,
+	"server_read_timeout":        15 * time.Second,
+	"server_read_header_timeout": 5 * time.Second,
+	"server_write_timeout":       30 * time.Second,
+	"server_idle_timeout":        time.Minute,
+	"server_max_header_bytes":    1 << 20,
+	"shutdown_timeout":           15 * time.Second,
+	"health_check_timeout":       2 * time.Second,
+	"log_level":                  "info",
+	"db_host":                    "localhost",
+	"db_port":                    "5432",
+	"db_sslmode":                 "disable",
+	"db_timezone":                "UTC",
+	"db_max_open_conns":          25,
+	"db_max_idle_conns":          10,
+	"db_conn_max_lifetime":       30 * time.Minute,
+	"db_connect_attempts":        5,
+	"db_connect_backoff":         time.Second,
+	"db_slow_query_threshold":    200 * time.Millisecond,
+	"migrate_on_start":           true,
+	"mail_dir":                   "outbox",
+	"upload_dir":                 "uploads",
+	"bcrypt_cost":                bcrypt.DefaultCost,
+	"password_min_length":        models.MinPasswordLength,
+	"password_max_length":        models.MaxPasswordLength,
+	"purge_mode":                 models.PurgeModeDelete,
+	"rate_limit_email":           "5/1h",
+	"rate_limit_login":           "10/1m",
+	"rate_limit_user":            "60/1m,20",
+	"access_token_ttl":           utils.DefaultAccessTokenTTL,
+	"refresh_token_ttl":          30 * 24 * time.Hour,
+	"tracing_exporter":           tracing.ExporterNone,
+	"tracing_service_name":       "user-api",
+	"tracing_file":               "traces.json",
+	"tracing_sample_ratio":       1.0,
 }
 
-func ConfigEnv() *Env {
-	viper.SetConfigName(".env")
-	viper.SetConfigType("env")
-	viper.AddConfigPath("/home/athul/Documents/Clean-Arch-User-api/")
+// legacyKeys maps the database keys of the original .env file to their
+// current names, so existing files keep working.
+var legacyKeys = map[string]string{
+	"user":     "db_user",
+	"password": "db_password",
+	"port":     "db_port",
+	"host":     "db_host",
+	"dbname":   "db_name",
+	"sslmode":  "db_sslmode",
+}
+
+// Load builds the configuration from, in increasing order of precedence,
+// built-in defaults, an optional config file, USERAPI_* environment
+// variables and command-line flags, then validates it. The config file is
+// given with --config or USERAPI_CONFIG; without either, a .env file in the
+// working directory is used if there is one.
+func Load(args []string) (*Env, error) {
+	v := viper.New()
+	flags := pflag.NewFlagSet("user-api", pflag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv(EnvPrefix+"_CONFIG"), "path to a config file (.env, .yaml, .json or .toml)")
+
+	v.SetEnvPrefix(EnvPrefix)
+	for _, field := range fields() {
+		value, ok := defaults[field.key]
+		if !ok {
+			value = reflect.Zero(field.typ).Interface()
+		}
+		v.SetDefault(field.key, value)
+		if err := v.BindEnv(field.key); err != nil {
+			return nil, err
+		}
+		if !field.secret {
+			flags.String(strings.ReplaceAll(field.key, "_", "-"), "", "sets "+field.key)
+		}
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	for _, field := range fields() {
+		if flag := flags.Lookup(strings.ReplaceAll(field.key, "_", "-")); flag != nil {
+			if err := v.BindPFlag(field.key, flag); err != nil {
+				return nil, err
+			}
+		}
+	}
 
-	if err := viper.ReadInConfig(); err != nil {
-		log.Fatal("Error loading .env:", err)
+	if err := readConfigFile(v, *configFile); err != nil {
+		return nil, err
 	}
-	fmt.Println("Loaded Config:", viper.AllSettings())
 
 	var env Env
-	env.DBUSER = viper.GetString("user")
-	env.DBPASSWORD = viper.GetString("password")
-	env.DBPORT = viper.GetString("port")
-	env.DBHOST = viper.GetString("host")
-	env.DBNAME = viper.GetString("dbname")
-	env.SSLMODE = viper.GetString("sslmode")
-	return &env
+	if err := v.Unmarshal(&env); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	if err := env.Validate(); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+func readConfigFile(v *viper.Viper, path string) error {
+	if path == "" {
+		if _, err := os.Stat(".env"); err != nil {
+			return nil
+		}
+		path = ".env"
+	}
+
+	v.SetConfigFile(path)
+	if strings.HasSuffix(path, ".env") {
+		v.SetConfigType("env")
+	}
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	// Legacy keys rank just above the defaults, so the current name wins
+	// wherever else it is set.
+	for legacy, key := range legacyKeys {
+		if v.InConfig(legacy) && !v.InConfig(key) {
+			v.SetDefault(key, v.Get(legacy))
+		}
+	}
+	return nil
+}
+
+// ValidationError lists every problem found in the configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the whole configuration and reports all problems at once.
+func (e *Env) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if e.SERVERPORT < 1 || e.SERVERPORT > 65535 {
+		add("server_port must be between 1 and 65535")
+	}
+	if e.SERVERREADTIMEOUT < 0 || e.SERVERREADHEADERTIMEOUT < 0 || e.SERVERWRITETIMEOUT < 0 || e.SERVERIDLETIMEOUT < 0 {
+		add("server timeouts must not be negative")
+	}
+	if e.SERVERMAXHEADERBYTES <= 0 {
+		add("server_max_header_bytes must be positive")
+	}
+	if e.SHUTDOWNTIMEOUT <= 0 {
+		add("shutdown_timeout must be positive")
+	}
+	if e.SHUTDOWNDELAY < 0 {
+		add("shutdown_delay must not be negative")
+	}
+	if e.HEALTHCHECKTIMEOUT <= 0 {
+		add("health_check_timeout must be positive")
+	}
+	if _, err := logrus.ParseLevel(e.LOGLEVEL); err != nil {
+		add("log_level %q is not a valid level", e.LOGLEVEL)
+	}
+
+	for key, value := range map[string]string{"db_host": e.DBHOST, "db_user": e.DBUSER, "db_name": e.DBNAME} {
+		if value == "" {
+			add("%s is required", key)
+		}
+	}
+	if port, err := strconv.Atoi(e.DBPORT); err != nil || port < 1 || port > 65535 {
+		add("db_port must be between 1 and 65535")
+	}
+	switch e.SSLMODE {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		add("db_sslmode %q is not a valid sslmode", e.SSLMODE)
+	}
+	if e.DBTIMEZONE == "" {
+		add("db_timezone is required")
+	}
+	if e.DBSTATEMENTTIMEOUT < 0 || e.DBCONNMAXLIFETIME < 0 || e.DBSLOWQUERYTHRESHOLD < 0 {
+		add("db_statement_timeout, db_conn_max_lifetime and db_slow_query_threshold must not be negative")
+	}
+	if e.DBMAXOPENCONNS < 0 || e.DBMAXIDLECONNS < 0 {
+		add("db_max_open_conns and db_max_idle_conns must not be negative")
+	} else if e.DBMAXOPENCONNS > 0 && e.DBMAXIDLECONNS > e.DBMAXOPENCONNS {
+		add("db_max_idle_conns must not exceed db_max_open_conns")
+	}
+	if e.DBCONNECTATTEMPTS < 1 {
+		add("db_connect_attempts must be at least 1")
+	}
+	if e.DBCONNECTBACKOFF <= 0 {
+		add("db_connect_backoff must be positive")
+	}
+
+	for key, value := range map[string]string{"verification_secret": e.VERIFICATIONSECRET, "mfa_encryption_key": e.MFAENCRYPTIONKEY} {
+		if value == "" {
+			add("%s is required", key)
+		}
+	}
+	if e.BCRYPTCOST < bcrypt.MinCost || e.BCRYPTCOST > bcrypt.MaxCost {
+		add("bcrypt_cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if e.PASSWORDMINLENGTH < 1 {
+		add("password_min_length must be at least 1")
+	}
+	if e.PASSWORDMAXLENGTH < e.PASSWORDMINLENGTH || e.PASSWORDMAXLENGTH > models.MaxPasswordLength {
+		add("password_max_length must be between password_min_length and %d", models.MaxPasswordLength)
+	}
+	if e.PASSWORDMINSTRENGTH < 0 || e.PASSWORDMINSTRENGTH > models.MaxPasswordStrength {
+		add("password_min_strength must be between 0 and %d", models.MaxPasswordStrength)
+	}
+	if e.PURGEMODE != models.PurgeModeDelete && e.PURGEMODE != models.PurgeModeAnonymize {
+		add("purge_mode must be %q or %q", models.PurgeModeDelete, models.PurgeModeAnonymize)
+	}
+	if e.DELETIONGRACEPERIOD < 0 {
+		add("deletion_grace_period must not be negative")
+	}
+	if e.ADMINEMAIL != "" && e.ADMINPASSWORD == "" {
+		add("admin_password is required when admin_email is set")
+	}
+
+	if e.LOGINMAXFAILURES < 0 || e.LOGINIPMAXFAILURES < 0 || e.LOGINLOCKOUTWINDOW < 0 {
+		add("login_max_failures, login_ip_max_failures and login_lockout_window must not be negative")
+	}
+	for _, proxy := range e.TrustedProxies() {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			add("trusted_proxies: %q is not an IP or CIDR", proxy)
+		}
+	}
+	for key, spec := range map[string]string{"rate_limit_email": e.RATELIMITEMAIL, "rate_limit_login": e.RATELIMITLOGIN, "rate_limit_user": e.RATELIMITUSER} {
+		if _, err := utils.ParseRateLimit(key, spec, nil); err != nil {
+			add("%s: %v", key, err)
+		}
+	}
+
+	if e.ACCESSTOKENTTL <= 0 {
+		add("access_token_ttl must be positive")
+	}
+	if e.REFRESHTOKENTTL <= e.ACCESSTOKENTTL {
+		add("refresh_token_ttl must be longer than access_token_ttl")
+	}
+	switch e.JWTALGORITHM {
+	case "", utils.AlgHS256:
+		if e.JWTSECRET == "" && e.JWTKEYFILE == "" {
+			add("jwt_secret or jwt_key_file is required")
+		} else if e.JWTSECRET != "" && len(e.JWTSECRET) < 32 {
+			add("jwt_secret must be at least 32 bytes")
+		}
+	case utils.AlgRS256, utils.AlgEdDSA:
+		if e.JWTKEYFILE == "" {
+			add("jwt_key_file is required for %s", e.JWTALGORITHM)
+		}
+	default:
+		add("jwt_algorithm must be one of %s, %s or %s", utils.AlgHS256, utils.AlgRS256, utils.AlgEdDSA)
+	}
+
+	switch e.TRACINGEXPORTER {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	case tracing.ExporterFile:
+		if e.TRACINGFILE == "" {
+			add("tracing_file is required for the file exporter")
+		}
+	default:
+		add("tracing_exporter must be one of %s, %s, %s or %s", tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterFile, tracing.ExporterOTLP)
+	}
+	if e.TRACINGSAMPLERATIO < 0 || e.TRACINGSAMPLERATIO > 1 {
+		add("tracing_sample_ratio must be between 0 and 1")
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return &ValidationError{Problems: problems}
+}
+
+// TrustedProxies splits TRUSTEDPROXIES into its entries.
+func (e *Env) TrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(e.TRUSTEDPROXIES, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// Redacted returns the configuration keyed by setting name, with the value
+// of every non-empty secret replaced.
+func (e Env) Redacted() map[string]interface{} {
+	settings := make(map[string]interface{})
+	value := reflect.ValueOf(e)
+	for i, field := range fields() {
+		current := value.Field(i).Interface()
+		if field.secret && !value.Field(i).IsZero() {
+			current = "[REDACTED]"
+		}
+		if d, ok := current.(time.Duration); ok {
+			current = d.String()
+		}
+		settings[field.key] = current
+	}
+	return settings
+}
+
+// String keeps secrets out of logs when the configuration is printed.
+func (e Env) String() string {
+	settings := e.Redacted()
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, settings[key]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+type field struct {
+	key    string
+	typ    reflect.Type
+	secret bool
+}
+
+// fields lists the settings of Env in declaration order.
+func fields() []field {
+	t := reflect.TypeOf(Env{})
+	list := make([]field, t.NumField())
+	for i := range list {
+		list[i] = field{
+			key:    t.Field(i).Tag.Get("mapstructure"),
+			typ:    t.Field(i).Type,
+			secret: t.Field(i).Tag.Get("secret") == "true",
+		}
+	}
+	return list
+}
+
+// IsHelp reports whether Load stopped because --help was given.
+func IsHelp(err error) bool {
+	return errors.Is(err, pflag.ErrHelp)
 }
@@ -0,0 +1,118 @@
+package config_test
+
+import (
+	"clean-arch/internal/app/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setSecrets provides the secrets every configuration needs.
+func setSecrets(t *testing.T) {
+	t.Setenv("USERAPI_VERIFICATION_SECRET", "verification-secret")
+	t.Setenv("USERAPI_MFA_ENCRYPTION_KEY", "mfa-encryption-key")
+	t.Setenv("USERAPI_JWT_SECRET", "a-jwt-secret-of-at-least-32-bytes")
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	setSecrets(t)
+	file := filepath.Join(t.TempDir(), "app.env")
+	assert.NoError(t, os.WriteFile(file, []byte("DB_USER=file\nDB_NAME=users\nLOG_LEVEL=warn\nSERVER_PORT=4000\n"), 0o600))
+
+	t.Setenv("USERAPI_CONFIG", file)
+	t.Setenv("USERAPI_LOG_LEVEL", "debug")
+	t.Setenv("USERAPI_SERVER_PORT", "5000")
+
+	env, err := config.Load([]string{"--server-port", "6000"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "file", env.DBUSER)
+	assert.Equal(t, "localhost", env.DBHOST)
+	assert.Equal(t, "debug", env.LOGLEVEL)
+	assert.Equal(t, 6000, env.SERVERPORT)
+	assert.Equal(t, 15*time.Minute, env.ACCESSTOKENTTL)
+}
+
+func TestLoad_LegacyDatabaseKeys(t *testing.T) {
+	setSecrets(t)
+	file := filepath.Join(t.TempDir(), ".env")
+	assert.NoError(t, os.WriteFile(file, []byte("USER=postgres\nPASSWORD=secret\nHOST=db\nPORT=5433\nDBNAME=users\nSSLMODE=require\n"), 0o600))
+
+	env, err := config.Load([]string{"--config", file})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "postgres", env.DBUSER)
+	assert.Equal(t, "secret", env.DBPASSWORD)
+	assert.Equal(t, "db", env.DBHOST)
+	assert.Equal(t, "5433", env.DBPORT)
+	assert.Equal(t, "require", env.SSLMODE)
+}
+
+func TestLoad_MissingConfigFile(t *testing.T) {
+	_, err := config.Load([]string{"--config", filepath.Join(t.TempDir(), "missing.env")})
+	assert.Error(t, err)
+}
+
+func TestLoad_SecretsAreNotFlags(t *testing.T) {
+	_, err := config.Load([]string{"--db-password", "secret"})
+	assert.Error(t, err)
+}
+
+func TestValidate_ReportsAllProblems(t *testing.T) {
+	t.Setenv("USERAPI_DB_USER", "postgres")
+	t.Setenv("USERAPI_BCRYPT_COST", "2")
+	t.Setenv("USERAPI_JWT_ALGORITHM", "RS256")
+
+	_, err := config.Load([]string{"--log-level", "loud", "--refresh-token-ttl", "1m", "--password-max-length", "100"})
+
+	var validationErr *config.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{
+		"bcrypt_cost must be between 4 and 31",
+		"db_name is required",
+		"jwt_key_file is required for RS256",
+		"log_level \"loud\" is not a valid level",
+		"mfa_encryption_key is required",
+		"password_max_length must be between password_min_length and 72",
+		"refresh_token_ttl must be longer than access_token_ttl",
+		"verification_secret is required",
+	}, validationErr.Problems)
+}
+
+func TestValidate_RequiresJWTKey(t *testing.T) {
+	setSecrets(t)
+	t.Setenv("USERAPI_DB_USER", "postgres")
+	t.Setenv("USERAPI_DB_NAME", "users")
+	t.Setenv("USERAPI_JWT_SECRET", "")
+
+	_, err := config.Load(nil)
+
+	var validationErr *config.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{"jwt_secret or jwt_key_file is required"}, validationErr.Problems)
+}
+
+func TestEnv_TrustedProxies(t *testing.T) {
+	assert.Empty(t, (&config.Env{}).TrustedProxies())
+
+	env := config.Env{TRUSTEDPROXIES: "10.0.0.0/8, 192.168.1.10,,proxy"}
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.10", "proxy"}, env.TrustedProxies())
+
+	var validationErr *config.ValidationError
+	assert.ErrorAs(t, env.Validate(), &validationErr)
+	assert.Contains(t, validationErr.Problems, `trusted_proxies: "proxy" is not an IP or CIDR`)
+}
+
+func TestEnv_RedactsSecrets(t *testing.T) {
+	env := config.Env{DBUSER: "postgres", DBPASSWORD: "hunter2", ACCESSTOKENTTL: time.Minute}
+
+	settings := env.Redacted()
+	assert.Equal(t, "postgres", settings["db_user"])
+	assert.Equal(t, "[REDACTED]", settings["db_password"])
+	assert.Equal(t, "", settings["jwt_secret"])
+	assert.Equal(t, "1m0s", settings["access_token_ttl"])
+	assert.NotContains(t, env.String(), "hunter2")
+}
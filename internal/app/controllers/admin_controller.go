@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"clean-arch/internal/app/utils"
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminController struct {
+	adminService services.AdminService
+}
+
+func NewAdminController(adminService services.AdminService) *AdminController {
+	return &AdminController{
+		adminService: adminService,
+	}
+}
+
+func (ac *AdminController) ListUsers(ctx *gin.Context) {
+	var query models.ListUsersQuery
+	if err := bindQuery(ctx, &query); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	filter := models.UserFilter{
+		Status:   query.Status,
+		Page:     query.Page,
+		Limit:    query.Limit,
+		SortDesc: query.Sort != "created_at",
+	}
+	filter.Normalize()
+
+	users, total, err := ac.adminService.ListUsers(ctx.Request.Context(), filter)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	response := make([]models.AdminUserResponse, 0, len(users))
+	for i := range users {
+		response = append(response, models.NewAdminUserResponse(&users[i]))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"users": response,
+		"total": total,
+		"page":  filter.Page,
+		"limit": filter.Limit,
+	})
+}
+
+func (ac *AdminController) GetUser(ctx *gin.Context) {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	user, err := ac.adminService.GetUser(ctx.Request.Context(), userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"user": models.NewAdminUserResponse(user)})
+}
+
+func (ac *AdminController) BlockUser(ctx *gin.Context) {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	var input models.BlockUserInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	claims, err := utils.GetClaims(ctx)
+	if err != nil {
+		ctx.Error(models.ErrUnauthorized)
+		return
+	}
+
+	if err := ac.adminService.BlockUser(ctx.Request.Context(), claims.ID, userID, input.Reason); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgUserBlocked})
+}
+
+func (ac *AdminController) UnblockUser(ctx *gin.Context) {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	claims, err := utils.GetClaims(ctx)
+	if err != nil {
+		ctx.Error(models.ErrUnauthorized)
+		return
+	}
+
+	if err := ac.adminService.UnblockUser(ctx.Request.Context(), claims.ID, userID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgUserUnblocked})
+}
+
+func (ac *AdminController) UnlockUser(ctx *gin.Context) {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ac.adminService.UnlockUser(ctx.Request.Context(), userID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgUserUnlocked})
+}
+
+func (ac *AdminController) ForceLogout(ctx *gin.Context) {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ac.adminService.ForceLogout(ctx.Request.Context(), userID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgUserLoggedOut})
+}
+
+func userIDParam(ctx *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || userID <= 0 {
+		ctx.Error(models.ErrInvalidID)
+		return 0, false
+	}
+	return userID, true
+}
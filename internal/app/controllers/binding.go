@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"clean-arch/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Binding validates input structs by their validate tags instead of Gin's
+// binding tags, so the rules live next to the json names in models.
+func init() {
+	binding.Validator = validation.Default
+}
+
+// bindJSON binds the request body into obj and reports every invalid field
+// at once, in the language the client asked for.
+func bindJSON(ctx *gin.Context, obj any) error {
+	if err := ctx.ShouldBindJSON(obj); err != nil {
+		return validation.Translate(err, ctx.GetHeader("Accept-Language"))
+	}
+	return nil
+}
+
+// bindQuery is bindJSON for query parameters.
+func bindQuery(ctx *gin.Context, obj any) error {
+	if err := ctx.ShouldBindQuery(obj); err != nil {
+		return validation.Translate(err, ctx.GetHeader("Accept-Language"))
+	}
+	return nil
+}
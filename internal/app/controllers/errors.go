@@ -0,0 +1,8 @@
+package controllers
+
+import "clean-arch/internal/core/models"
+
+// badRequest reports invalid input that has no error of its own in models.
+func badRequest(message string) error {
+	return models.NewError(models.CodeValidation, message)
+}
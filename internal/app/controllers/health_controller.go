@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"clean-arch/internal/health"
+	"clean-arch/internal/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct {
+	health *health.Health
+}
+
+func NewHealthController(health *health.Health) *HealthController {
+	return &HealthController{
+		health: health,
+	}
+}
+
+// Liveness reports that the process is up. It checks no dependencies, so a
+// database outage does not get the process restarted.
+func (hc *HealthController) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness reports whether the process can serve traffic, with the status
+// of every dependency check. Why a check failed is logged, not returned.
+func (hc *HealthController) Readiness(ctx *gin.Context) {
+	report := hc.health.Check(ctx.Request.Context())
+	for name, result := range report.Checks {
+		if result.Status != health.CheckUp {
+			logger.FromContext(ctx.Request.Context()).WithFields(logger.Fields{"check": name}).Error("Readiness check failed", result.Error)
+		}
+	}
+	if !report.Ready() {
+		ctx.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"clean-arch/internal/app/utils"
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAController struct {
+	mfaService services.MFAService
+}
+
+func NewMFAController(mfaService services.MFAService) *MFAController {
+	return &MFAController{
+		mfaService: mfaService,
+	}
+}
+
+func (mc *MFAController) BeginEnrollment(ctx *gin.Context) {
+	claims, err := utils.GetClaims(ctx)
+	if err != nil {
+		ctx.Error(models.ErrUnauthorized)
+		return
+	}
+
+	enrollment, err := mc.mfaService.BeginEnrollment(ctx.Request.Context(), claims.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollment)
+}
+
+func (mc *MFAController) ConfirmEnrollment(ctx *gin.Context) {
+	claims, err := utils.GetClaims(ctx)
+	if err != nil {
+		ctx.Error(models.ErrUnauthorized)
+		return
+	}
+
+	var input models.MFACodeInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	codes, err := mc.mfaService.ConfirmEnrollment(ctx.Request.Context(), claims.ID, input.Code)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":        models.MsgMFAEnabled,
+		"recovery_codes": codes,
+	})
+}
+
+func (mc *MFAController) Disable(ctx *gin.Context) {
+	claims, err := utils.GetClaims(ctx)
+	if err != nil {
+		ctx.Error(models.ErrUnauthorized)
+		return
+	}
+
+	var input models.MFADisableInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := mc.mfaService.Disable(ctx.Request.Context(), claims.ID, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgMFADisabled})
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"clean-arch/internal/app/utils"
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordController struct {
+	passwordService services.PasswordService
+}
+
+func NewPasswordController(passwordService services.PasswordService) *PasswordController {
+	return &PasswordController{
+		passwordService: passwordService,
+	}
+}
+
+func (pc *PasswordController) ForgotPassword(ctx *gin.Context) {
+	var input models.ForgotPasswordInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := pc.passwordService.ForgotPassword(ctx.Request.Context(), input.Email); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgPasswordResetEmailSent})
+}
+
+func (pc *PasswordController) ResetPassword(ctx *gin.Context) {
+	var input models.ResetPasswordInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := pc.passwordService.ResetPassword(ctx.Request.Context(), &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgPasswordResetSuccessfully})
+}
+
+func (pc *PasswordController) ChangePassword(ctx *gin.Context) {
+	claims, err := utils.GetClaims(ctx)
+	if err != nil {
+		ctx.Error(models.ErrUnauthorized)
+		return
+	}
+
+	var input models.PasswordReset
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := pc.passwordService.ChangePassword(ctx.Request.Context(), claims.ID, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgPasswordChanged})
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"clean-arch/internal/app/utils"
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleController struct {
+	roleService services.RoleService
+}
+
+func NewRoleController(roleService services.RoleService) *RoleController {
+	return &RoleController{
+		roleService: roleService,
+	}
+}
+
+func (rc *RoleController) ListRoles(ctx *gin.Context) {
+	roles, err := rc.roleService.ListRoles(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+func (rc *RoleController) ListPermissions(ctx *gin.Context) {
+	permissions, err := rc.roleService.ListPermissions(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"permissions": permissions})
+}
+
+func (rc *RoleController) CreateRole(ctx *gin.Context) {
+	var input models.CreateRoleInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	role, err := rc.roleService.CreateRole(ctx.Request.Context(), &input)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"role": role})
+}
+
+func (rc *RoleController) SetRolePermissions(ctx *gin.Context) {
+	var input models.RolePermissionsInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	role, err := rc.roleService.SetRolePermissions(ctx.Request.Context(), ctx.Param("name"), input.Permissions)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"role": role})
+}
+
+func (rc *RoleController) DeleteRole(ctx *gin.Context) {
+	if err := rc.roleService.DeleteRole(ctx.Request.Context(), ctx.Param("name")); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgRoleDeleted})
+}
+
+func (rc *RoleController) AssignRole(ctx *gin.Context) {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	var input models.AssignRoleInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := rc.roleService.AssignRole(ctx.Request.Context(), userID, input.Role); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgRoleAssigned})
+}
+
+func (rc *RoleController) RevokeRole(ctx *gin.Context) {
+	userID, ok := userIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	claims, err := utils.GetClaims(ctx)
+	if err != nil {
+		ctx.Error(models.ErrUnauthorized)
+		return
+	}
+
+	if err := rc.roleService.RevokeRole(ctx.Request.Context(), claims.ID, userID, ctx.Param("role")); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgRoleRevoked})
+}
@@ -4,8 +4,13 @@ import (
 	"clean-arch/internal/app/utils"
 	"clean-arch/internal/core/models"
 	"clean-arch/internal/core/services"
+	"clean-arch/internal/logger"
+	"clean-arch/internal/metrics"
+	"context"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,72 +18,215 @@ import (
 
 type UserController struct {
 	userService    services.UserService
+	tokenService   services.TokenService
+	roleService    services.RoleService
+	mfaService     services.MFAService
+	loginGuard     services.LoginGuard
 	tokenGenerator utils.TokenGenerator
 }
 
-func NewUserController(userService services.UserService, tokenGenerator utils.TokenGenerator) *UserController {
+func NewUserController(userService services.UserService, tokenService services.TokenService, roleService services.RoleService, mfaService services.MFAService, loginGuard services.LoginGuard, tokenGenerator utils.TokenGenerator) *UserController {
 	return &UserController{
 		userService:    userService,
+		tokenService:   tokenService,
+		roleService:    roleService,
+		mfaService:     mfaService,
+		loginGuard:     loginGuard,
 		tokenGenerator: tokenGenerator,
 	}
 }
 
 func (uc *UserController) SignUp(ctx *gin.Context) {
-	var input models.SignupInput
-	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
-		return
-	}
+	result := metrics.SignupError
+	defer func() {
+		metrics.Signups.WithLabelValues(result).Inc()
+		logger.FromContext(ctx.Request.Context()).WithFields(logger.Fields{"result": result}).Info("Signup attempt")
+	}()
 
-	if err := models.ValidateSignup(input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var input models.SignupInput
+	if err := bindJSON(ctx, &input); err != nil {
+		result = metrics.SignupInvalid
+		ctx.Error(err)
 		return
 	}
 
-	if err := uc.userService.SignUp(&input); err != nil {
+	if err := uc.userService.SignUp(ctx.Request.Context(), &input); err != nil {
 		if errors.Is(err, models.ErrUserAlreadyExists) {
-			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-		} else {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			result = metrics.SignupExists
 		}
+		ctx.Error(err)
 		return
 	}
 
+	result = metrics.SignupSuccess
 	ctx.JSON(http.StatusCreated, gin.H{"message": "User signed up successfully!"})
 }
 
+func (c *UserController) VerifyEmail(ctx *gin.Context) {
+	var input models.VerifyEmailInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := c.userService.VerifyEmail(ctx.Request.Context(), input.Token); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgEmailVerifiedSuccessfully})
+}
+
+func (c *UserController) ResendVerification(ctx *gin.Context) {
+	var input models.ResendVerificationInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := c.userService.ResendVerification(ctx.Request.Context(), input.Email); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgVerificationEmailResent})
+}
+
 func (c *UserController) Login(ctx *gin.Context) {
+	result := metrics.LoginError
+	defer func() {
+		metrics.Logins.WithLabelValues(result).Inc()
+		logger.FromContext(ctx.Request.Context()).WithFields(logger.Fields{"result": result}).Info("Login attempt")
+	}()
+
 	var input models.LoginInput
-	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+	if err := bindJSON(ctx, &input); err != nil {
+		result = metrics.LoginInvalid
+		ctx.Error(err)
 		return
 	}
 
-	if input.Email == "" || input.Password == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Email and password are required"})
+	if err := c.loginGuard.Check(input.Email, ctx.ClientIP()); err != nil {
+		result = metrics.LoginThrottled
+		respondThrottled(ctx, err)
 		return
 	}
 
-	user, err := c.userService.Login(input.Email, input.Password)
+	user, err := c.userService.Login(ctx.Request.Context(), input.Email, input.Password)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		if errors.Is(err, models.ErrEmailNotVerified) {
+			result = metrics.LoginUnverified
+			ctx.Error(err)
+			return
+		}
+		if err := c.loginGuard.RecordFailure(input.Email, ctx.ClientIP()); err != nil {
+			ctx.Error(err)
+			return
+		}
+		// A blocked account answers like a wrong password, so the response
+		// does not confirm that the password was right.
+		result = metrics.LoginFailure
+		if errors.Is(err, models.ErrUserBlocked) {
+			result = metrics.LoginBlocked
+		}
+		ctx.Error(loginFailure(err, models.ErrInvalidCredentials))
+		return
+	}
+
+	if user.MFAEnabled {
+		mfaToken, err := c.tokenGenerator.CreateMFAToken(user.ID, user.Email)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+
+		result = metrics.LoginMFARequired
+		ctx.JSON(http.StatusOK, gin.H{
+			"message":      models.MsgMFARequired,
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		})
+		return
+	}
+
+	if err := c.startSession(ctx, user); err != nil {
+		ctx.Error(err)
+		return
+	}
+	result = metrics.LoginSuccess
+}
+
+// LoginMFA is the second login step for users with two-factor
+// authentication: it exchanges the mfa_pending token and a TOTP or recovery
+// code for a session.
+func (c *UserController) LoginMFA(ctx *gin.Context) {
+	var input models.MFALoginInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	claims, err := c.tokenGenerator.ParseToken(input.MFAToken)
+	if err != nil || claims.Purpose != utils.PurposeMFAPending {
+		ctx.Error(models.ErrInvalidMFAToken)
 		return
 	}
 
-	if user.Status == "Blocked" {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User is blocked"})
+	if err := c.loginGuard.Check(claims.Email, ctx.ClientIP()); err != nil {
+		respondThrottled(ctx, err)
 		return
 	}
 
-	token, err := c.tokenGenerator.CreateToken(int(user.ID), input.Email, "user")
+	user, err := c.mfaService.Verify(ctx.Request.Context(), claims.ID, input.Code)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		if errors.Is(err, models.ErrInvalidMFACode) {
+			if err := c.loginGuard.RecordFailure(claims.Email, ctx.ClientIP()); err != nil {
+				ctx.Error(err)
+				return
+			}
+		}
+
+		ctx.Error(loginFailure(err, models.ErrInvalidMFACode))
+		return
+	}
+
+	if user.Status == models.StatusBlocked {
+		ctx.Error(models.ErrUserBlocked)
 		return
 	}
 
+	// The pending token is single-use.
+	if err := c.tokenService.Logout(ctx.Request.Context(), claims.ID, claims.Id, time.Unix(claims.ExpiresAt, 0), ""); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := c.startSession(ctx, user); err != nil {
+		ctx.Error(err)
+	}
+}
+
+// startSession issues the access and refresh tokens at the end of a login.
+// Only a complete login, including the MFA step, clears the failure counter.
+func (c *UserController) startSession(ctx *gin.Context, user *models.User) error {
+	if err := c.loginGuard.RecordSuccess(user.Email); err != nil {
+		return err
+	}
+
+	token, err := c.createAccessToken(ctx.Request.Context(), user)
+	if err != nil {
+		return err
+	}
+
+	refreshToken, err := c.tokenService.IssueRefreshToken(ctx.Request.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
 	response := map[string]interface{}{
-		"message": "Login successful",
-		"token":   token,
+		"message":       "Login successful",
+		"token":         token,
+		"refresh_token": refreshToken,
 		"user": map[string]interface{}{
 			"id":           user.ID,
 			"user_name":    user.UserName,
@@ -91,33 +239,223 @@ func (c *UserController) Login(ctx *gin.Context) {
 	}
 
 	ctx.JSON(http.StatusOK, response)
+	return nil
 }
 
 func (c *UserController) GetProfile(ctx *gin.Context) {
 	claims, exists := ctx.Get("claims")
 	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		ctx.Error(models.ErrUnauthorized)
 		return
 	}
 
 	customClaims, ok := claims.(*utils.Claims)
 	if !ok || customClaims.Email == "" {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		ctx.Error(models.ErrUnauthorized)
 		return
 	}
 
-	user, err := c.userService.GetProfile(customClaims.ID)
+	user, err := c.userService.GetProfile(ctx.Request.Context(), customClaims.ID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	profileResponse := models.UserProfileResponse{
+	ctx.JSON(http.StatusOK, gin.H{"user": newProfileResponse(user)})
+}
+
+func (c *UserController) UpdateProfile(ctx *gin.Context) {
+	claims, err := utils.GetClaims(ctx)
+	if err != nil {
+		ctx.Error(models.ErrUnauthorized)
+		return
+	}
+
+	var input models.UpdateProfileInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	user, err := c.userService.UpdateProfile(ctx.Request.Context(), claims.ID, &input)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	response := gin.H{
+		"message": models.MsgProfileUpdatedSuccessfully,
+		"user":    newProfileResponse(user),
+	}
+	if user.PendingEmail != "" {
+		response["pending_email"] = user.PendingEmail
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (c *UserController) UploadProfilePicture(ctx *gin.Context) {
+	claims, err := utils.GetClaims(ctx)
+	if err != nil {
+		ctx.Error(models.ErrUnauthorized)
+		return
+	}
+
+	// Leave room for the multipart envelope around the file itself.
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, models.MaxProfilePictureSize+1<<20)
+
+	fileHeader, err := ctx.FormFile("picture")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.Error(models.ErrFileTooLarge)
+			return
+		}
+		ctx.Error(badRequest("Picture file is required"))
+		return
+	}
+
+	if fileHeader.Size > models.MaxProfilePictureSize {
+		ctx.Error(models.ErrFileTooLarge)
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.Error(badRequest("Invalid file"))
+		return
+	}
+	defer file.Close()
+
+	user, err := c.userService.UploadProfilePicture(ctx.Request.Context(), claims.ID, file)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":    models.MsgProfilePictureUploaded,
+		"avatar_url": user.AvatarURL,
+	})
+}
+
+func (c *UserController) DeleteAccount(ctx *gin.Context) {
+	claims, err := utils.GetClaims(ctx)
+	if err != nil {
+		ctx.Error(models.ErrUnauthorized)
+		return
+	}
+
+	if err := c.userService.DeleteAccount(ctx.Request.Context(), claims.ID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := c.tokenService.LogoutAll(ctx.Request.Context(), claims.ID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgAccountDeleted})
+}
+
+// respondThrottled rejects a login attempt stopped by the LoginGuard, telling
+// the client when to retry.
+func respondThrottled(ctx *gin.Context, err error) {
+	var throttled *models.LoginThrottledError
+	if errors.As(err, &throttled) {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	}
+	ctx.Error(err)
+}
+
+// loginFailure reports every rejection of a login step as unauthorized with
+// the message of public, so that clients cannot tell an unknown account from
+// wrong credentials. Internal errors are passed on as they are.
+func loginFailure(err error, public *models.Error) error {
+	if models.ErrorCodeOf(err) == models.CodeInternal || errors.Is(err, models.ErrInvalidCredentials) {
+		return err
+	}
+	return models.Wrap(models.CodeUnauthorized, public.Message, err)
+}
+
+// createAccessToken signs the user's current roles and permissions into a
+// new access token.
+func (c *UserController) createAccessToken(ctx context.Context, user *models.User) (string, error) {
+	access, err := c.roleService.UserAccess(ctx, user)
+	if err != nil {
+		return "", err
+	}
+	return c.tokenGenerator.CreateToken(user.ID, user.Email, access)
+}
+
+func newProfileResponse(user *models.User) models.UserProfileResponse {
+	return models.UserProfileResponse{
 		Name:      user.UserName,
 		Email:     user.Email,
 		PhnNumber: user.PhoneNumber,
 		Status:    user.Status,
+		AvatarURL: user.AvatarURL,
+	}
+}
+
+func (c *UserController) RefreshToken(ctx *gin.Context) {
+	var input models.RefreshTokenInput
+	if err := bindJSON(ctx, &input); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	user, refreshToken, err := c.tokenService.RotateRefreshToken(ctx.Request.Context(), input.RefreshToken)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	token, err := c.createAccessToken(ctx.Request.Context(), user)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":       models.MsgTokenRefreshed,
+		"token":         token,
+		"refresh_token": refreshToken,
+	})
+}
+
+func (c *UserController) Logout(ctx *gin.Context) {
+	claims, err := utils.GetClaims(ctx)
+	if err != nil {
+		ctx.Error(models.ErrUnauthorized)
+		return
+	}
+
+	// The refresh token is optional; without it only the access token is revoked.
+	var input models.LogoutInput
+	_ = ctx.ShouldBindJSON(&input)
+
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+	if err := c.tokenService.Logout(ctx.Request.Context(), claims.ID, claims.Id, expiresAt, input.RefreshToken); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgLogoutSuccessful})
+}
+
+func (c *UserController) LogoutAll(ctx *gin.Context) {
+	claims, err := utils.GetClaims(ctx)
+	if err != nil {
+		ctx.Error(models.ErrUnauthorized)
+		return
+	}
+
+	if err := c.tokenService.LogoutAll(ctx.Request.Context(), claims.ID); err != nil {
+		ctx.Error(err)
+		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"user": profileResponse})
+	ctx.JSON(http.StatusOK, gin.H{"message": models.MsgLogoutAllSuccessful})
 }
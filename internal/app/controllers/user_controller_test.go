@@ -5,28 +5,48 @@ import (
 	"clean-arch/internal/app/controllers"
 	"clean-arch/internal/app/utils"
 	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"clean-arch/internal/core/services"
+	"clean-arch/internal/mailer"
+	"clean-arch/internal/metrics"
+	"clean-arch/internal/mocks"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type MockUserService struct {
 	mock.Mock
 }
 
-func (m *MockUserService) SignUp(user *models.SignupInput) error {
+func (m *MockUserService) SignUp(ctx context.Context, user *models.SignupInput) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserService) Login(email, password string) (*models.User, error) {
+func (m *MockUserService) VerifyEmail(ctx context.Context, token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ResendVerification(ctx context.Context, email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *MockUserService) Login(ctx context.Context, email, password string) (*models.User, error) {
 	args := m.Called(email, password)
 	if user, ok := args.Get(0).(*models.User); ok {
 		return user, args.Error(1)
@@ -34,7 +54,7 @@ func (m *MockUserService) Login(email, password string) (*models.User, error) {
 	return nil, args.Error(1)
 }
 
-func (m *MockUserService) GetProfile(userID int) (*models.User, error) {
+func (m *MockUserService) GetProfile(ctx context.Context, userID int) (*models.User, error) {
 	args := m.Called(userID)
 	if user, ok := args.Get(0).(*models.User); ok {
 		return user, args.Error(1)
@@ -42,25 +62,211 @@ func (m *MockUserService) GetProfile(userID int) (*models.User, error) {
 	return nil, args.Error(1)
 }
 
+func (m *MockUserService) UpdateProfile(ctx context.Context, userID int, input *models.UpdateProfileInput) (*models.User, error) {
+	args := m.Called(userID, input)
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserService) UploadProfilePicture(ctx context.Context, userID int, file io.Reader) (*models.User, error) {
+	args := m.Called(userID, file)
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserService) DeleteAccount(ctx context.Context, userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserService) PurgeDeletedAccounts(ctx context.Context) (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockUserService) PurgeExpiredRegistrations(ctx context.Context) (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockTokenService struct {
+	mock.Mock
+}
+
+func (m *MockTokenService) IssueRefreshToken(ctx context.Context, userID int) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTokenService) RotateRefreshToken(ctx context.Context, refreshToken string) (*models.User, string, error) {
+	args := m.Called(refreshToken)
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.String(1), args.Error(2)
+	}
+	return nil, args.String(1), args.Error(2)
+}
+
+func (m *MockTokenService) Logout(ctx context.Context, userID int, jti string, expiresAt time.Time, refreshToken string) error {
+	args := m.Called(userID, jti, expiresAt, refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockTokenService) LogoutAll(ctx context.Context, userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockTokenService) PurgeExpired(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+type MockRoleService struct {
+	mock.Mock
+}
+
+func (m *MockRoleService) ListRoles(ctx context.Context) ([]models.Role, error) {
+	args := m.Called()
+	if roles, ok := args.Get(0).([]models.Role); ok {
+		return roles, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRoleService) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	args := m.Called()
+	if permissions, ok := args.Get(0).([]models.Permission); ok {
+		return permissions, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRoleService) CreateRole(ctx context.Context, input *models.CreateRoleInput) (*models.Role, error) {
+	args := m.Called(input)
+	if role, ok := args.Get(0).(*models.Role); ok {
+		return role, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRoleService) SetRolePermissions(ctx context.Context, name string, permissions []string) (*models.Role, error) {
+	args := m.Called(name, permissions)
+	if role, ok := args.Get(0).(*models.Role); ok {
+		return role, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRoleService) DeleteRole(ctx context.Context, name string) error {
+	args := m.Called(name)
+	return args.Error(0)
+}
+
+func (m *MockRoleService) AssignRole(ctx context.Context, userID int, name string) error {
+	args := m.Called(userID, name)
+	return args.Error(0)
+}
+
+func (m *MockRoleService) RevokeRole(ctx context.Context, actorID, userID int, name string) error {
+	args := m.Called(actorID, userID, name)
+	return args.Error(0)
+}
+
+func (m *MockRoleService) UserAccess(ctx context.Context, user *models.User) (*models.UserAccess, error) {
+	args := m.Called(user)
+	if access, ok := args.Get(0).(*models.UserAccess); ok {
+		return access, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRoleService) SeedDefaults(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+type MockMFAService struct {
+	mock.Mock
+}
+
+func (m *MockMFAService) BeginEnrollment(ctx context.Context, userID int) (*models.MFAEnrollment, error) {
+	args := m.Called(userID)
+	if enrollment, ok := args.Get(0).(*models.MFAEnrollment); ok {
+		return enrollment, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) ConfirmEnrollment(ctx context.Context, userID int, code string) ([]string, error) {
+	args := m.Called(userID, code)
+	if codes, ok := args.Get(0).([]string); ok {
+		return codes, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) Disable(ctx context.Context, userID int, input *models.MFADisableInput) error {
+	args := m.Called(userID, input)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) Verify(ctx context.Context, userID int, code string) (*models.User, error) {
+	args := m.Called(userID, code)
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func newLoginGuard() *services.LoginGuardImpl {
+	return services.NewLoginGuard(repository.NewInMemoryLoginAttemptStore(), services.LoginGuardConfig{})
+}
+
 type MockTokenGenerator struct{}
 
-func (m *MockTokenGenerator) GenerateToken(userID int, email, role string) (string, error) {
+func (m *MockTokenGenerator) GenerateToken(userID int, email string, access *models.UserAccess) (string, error) {
 	return "mocked-jwt-token", nil
 }
 
-func (m *MockTokenGenerator) CreateToken(id int, email, role string) (string, error) {
-	return m.GenerateToken(id, email, role)
+func (m *MockTokenGenerator) CreateToken(id int, email string, access *models.UserAccess) (string, error) {
+	return m.GenerateToken(id, email, access)
+}
+
+func (m *MockTokenGenerator) CreateMFAToken(id int, email string) (string, error) {
+	return "mocked-mfa-token", nil
+}
+
+func (m *MockTokenGenerator) ParseToken(tokenString string) (*utils.Claims, error) {
+	return nil, errors.New("not implemented")
+}
+
+// assertProblem checks that rec is a problem+json response with the given
+// code and detail.
+func assertProblem(t *testing.T, rec *httptest.ResponseRecorder, code models.ErrorCode, detail string) {
+	t.Helper()
+
+	var problem utils.Problem
+	assert.Equal(t, utils.ProblemContentType, rec.Header().Get("Content-Type"))
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, code, problem.Code)
+	assert.Equal(t, rec.Code, problem.Status)
+	assert.Equal(t, detail, problem.Detail)
 }
 
 func TestSignUp(t *testing.T) {
 	mockUserService := new(MockUserService)
 	mockTokenGenerator := new(MockTokenGenerator)
 
-	userController := controllers.NewUserController(mockUserService, mockTokenGenerator)
+	userController := controllers.NewUserController(mockUserService, new(MockTokenService), new(MockRoleService), new(MockMFAService), newLoginGuard(), mockTokenGenerator)
 
 	gin.SetMode(gin.TestMode)
 
 	router := gin.Default()
+	router.Use(utils.ErrorHandler())
 	router.POST("/signup", userController.SignUp)
 
 	input := models.SignupInput{
@@ -88,9 +294,10 @@ func TestSignUp(t *testing.T) {
 func TestUserController_SignUp_UserExists(t *testing.T) {
 	mockService := new(MockUserService)
 	mockTokenGenerator := new(MockTokenGenerator)
-	controller := controllers.NewUserController(mockService, mockTokenGenerator)
+	controller := controllers.NewUserController(mockService, new(MockTokenService), new(MockRoleService), new(MockMFAService), newLoginGuard(), mockTokenGenerator)
 
 	router := gin.Default()
+	router.Use(utils.ErrorHandler())
 	router.POST("/signup", controller.SignUp)
 
 	input := models.SignupInput{
@@ -109,20 +316,59 @@ func TestUserController_SignUp_UserExists(t *testing.T) {
 	router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusConflict, rec.Code)
-	assert.JSONEq(t, `{"error": "User already exists"}`, rec.Body.String())
+	assertProblem(t, rec, models.CodeConflict, "User already exists")
 
 	mockService.AssertExpectations(t)
 }
+
+func TestSignUp_ReportsInvalidFields(t *testing.T) {
+	mockService := new(MockUserService)
+	controller := controllers.NewUserController(mockService, new(MockTokenService), new(MockRoleService), new(MockMFAService), newLoginGuard(), new(MockTokenGenerator))
+
+	router := gin.Default()
+	router.Use(utils.ErrorHandler())
+	router.POST("/signup", controller.SignUp)
+
+	body, _ := json.Marshal(models.SignupInput{
+		UserName:    "athul",
+		Email:       "not-an-email",
+		PhoneNumber: "1234567890",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/signup", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assertProblem(t, rec, models.CodeValidation, "Validation failed")
+
+	var problem utils.Problem
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, []models.FieldError{
+		{Field: "email", Message: "email must be a valid email address", Rule: "email"},
+		{Field: "password", Message: "password is a required field", Rule: "required"},
+	}, problem.Errors)
+	mockService.AssertNotCalled(t, "SignUp")
+}
+
 func TestLogin_Success(t *testing.T) {
 
+	access := &models.UserAccess{Roles: []string{"user"}}
+
 	mockTokenGenerator := new(utils.MockTokenGenerator)
 
-	mockTokenGenerator.On("CreateToken", 1, "johndoe@gmail.com", "user").Return("mocked-jwt-token", nil)
+	mockTokenGenerator.On("CreateToken", 1, "johndoe@gmail.com", access).Return("mocked-jwt-token", nil)
 
 	mockService := new(MockUserService)
-	controller := controllers.NewUserController(mockService, mockTokenGenerator)
+	mockTokenService := new(MockTokenService)
+	mockTokenService.On("IssueRefreshToken", 1).Return("mocked-refresh-token", nil)
+	mockRoleService := new(MockRoleService)
+	mockRoleService.On("UserAccess", mock.AnythingOfType("*models.User")).Return(access, nil)
+	controller := controllers.NewUserController(mockService, mockTokenService, mockRoleService, new(MockMFAService), newLoginGuard(), mockTokenGenerator)
 
 	router := gin.Default()
+	router.Use(utils.ErrorHandler())
 	router.POST("/login", controller.Login)
 
 	input := models.LoginInput{
@@ -141,6 +387,7 @@ func TestLogin_Success(t *testing.T) {
 	}
 
 	mockService.On("Login", input.Email, input.Password).Return(mockUser, nil)
+	successes := testutil.ToFloat64(metrics.Logins.WithLabelValues(metrics.LoginSuccess))
 
 	mockUser.CreatedAt = mockUser.CreatedAt.Truncate(time.Second)
 	mockUser.UpdatedAt = mockUser.UpdatedAt.Truncate(time.Second)
@@ -155,8 +402,9 @@ func TestLogin_Success(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec.Code)
 
 	expected := map[string]interface{}{
-		"message": "Login successful",
-		"token":   "mocked-jwt-token",
+		"message":       "Login successful",
+		"token":         "mocked-jwt-token",
+		"refresh_token": "mocked-refresh-token",
 		"user": map[string]interface{}{
 			"id":           1.0,
 			"user_name":    "JohnDoe",
@@ -174,17 +422,21 @@ func TestLogin_Success(t *testing.T) {
 	}
 
 	assert.JSONEq(t, string(expectedJSON), rec.Body.String())
+	assert.Equal(t, successes+1, testutil.ToFloat64(metrics.Logins.WithLabelValues(metrics.LoginSuccess)))
 
 	mockService.AssertExpectations(t)
+	mockTokenService.AssertExpectations(t)
+	mockRoleService.AssertExpectations(t)
 	mockTokenGenerator.AssertExpectations(t)
 }
 
 func TestLogin_InvalidCredentials(t *testing.T) {
 	mockService := new(MockUserService)
 	mockTokenGenerator := new(MockTokenGenerator)
-	controller := controllers.NewUserController(mockService, mockTokenGenerator)
+	controller := controllers.NewUserController(mockService, new(MockTokenService), new(MockRoleService), new(MockMFAService), newLoginGuard(), mockTokenGenerator)
 
 	router := gin.Default()
+	router.Use(utils.ErrorHandler())
 	router.POST("/login", controller.Login)
 
 	input := models.LoginInput{
@@ -192,7 +444,8 @@ func TestLogin_InvalidCredentials(t *testing.T) {
 		Password: "wrongpassword",
 	}
 
-	mockService.On("Login", input.Email, input.Password).Return(nil, errors.New("Invalid credentials"))
+	mockService.On("Login", input.Email, input.Password).Return(nil, models.ErrInvalidCredentials)
+	failures := testutil.ToFloat64(metrics.Logins.WithLabelValues(metrics.LoginFailure))
 
 	body, _ := json.Marshal(input)
 	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
@@ -203,38 +456,309 @@ func TestLogin_InvalidCredentials(t *testing.T) {
 
 	assert.Equal(t, http.StatusUnauthorized, rec.Code)
 
-	expected := `{"error": "Invalid credentials"}`
-	assert.JSONEq(t, expected, rec.Body.String())
+	assertProblem(t, rec, models.CodeUnauthorized, "Invalid email or password")
+	assert.Equal(t, failures+1, testutil.ToFloat64(metrics.Logins.WithLabelValues(metrics.LoginFailure)))
 
 	mockService.AssertExpectations(t)
 }
 
-func TestLogin_UserNotFound(t *testing.T) {
-	mockService := new(MockUserService)
-	mockTokenGenerator := new(MockTokenGenerator)
-	controller := controllers.NewUserController(mockService, mockTokenGenerator)
+// TestLogin_FailuresAreIndistinguishable runs the real UserService so that
+// every way a login can fail before the password is proven goes through the
+// same code path as in production.
+func TestLogin_FailuresAreIndistinguishable(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("johndoe123"), bcrypt.MinCost)
+
+	userRepo := new(mocks.MockUserRepository)
+	pendingRepo := new(mocks.MockPendingUserRepository)
+	userRepo.On("FindUserByEmail", mock.Anything, "johndoe@gmail.com").Return(&models.User{ID: 1, Email: "johndoe@gmail.com", Password: string(hash), Status: models.StatusActive}, nil)
+	userRepo.On("FindUserByEmail", mock.Anything, "blocked@gmail.com").Return(&models.User{ID: 2, Email: "blocked@gmail.com", Password: string(hash), Status: models.StatusBlocked}, nil)
+	userRepo.On("FindUserByEmail", mock.Anything, mock.Anything).Return(nil, models.ErrUserDoesNotExist)
+	userRepo.On("FindDeletedUserByEmail", mock.Anything, mock.Anything).Return(nil, models.ErrUserDoesNotExist)
+	pendingRepo.On("FindPendingUserByEmail", "pending@gmail.com").Return(&models.TempUser{Email: "pending@gmail.com", Password: string(hash)}, nil)
+	pendingRepo.On("FindPendingUserByEmail", mock.Anything).Return(nil, repository.ErrPendingUserNotFound)
+
+	userService := services.NewUserService(userRepo, pendingRepo, mailer.NewMemoryMailer(), nil, services.UserServiceConfig{})
+	controller := controllers.NewUserController(userService, new(MockTokenService), new(MockRoleService), new(MockMFAService), newLoginGuard(), new(MockTokenGenerator))
 
 	router := gin.Default()
+	router.Use(utils.ErrorHandler())
 	router.POST("/login", controller.Login)
 
-	input := models.LoginInput{
-		Email:    "notfound@gmail.com",
-		Password: "somepassword",
+	// Each attempt comes from its own address, so that the IP backoff of the
+	// LoginGuard does not throttle the next one.
+	clients := 0
+	login := func(email, password string) *httptest.ResponseRecorder {
+		clients++
+		body, _ := json.Marshal(models.LoginInput{Email: email, Password: password})
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", clients)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	wrongPassword := login("johndoe@gmail.com", "wrongpassword")
+	assert.Equal(t, http.StatusUnauthorized, wrongPassword.Code)
+	assertProblem(t, wrongPassword, models.CodeUnauthorized, models.ErrInvalidCredentials.Message)
+
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"unknown email":              login("nobody@gmail.com", "wrongpassword"),
+		"unverified, wrong password": login("pending@gmail.com", "wrongpassword"),
+		"blocked, right password":    login("blocked@gmail.com", "johndoe123"),
+	} {
+		assert.Equal(t, wrongPassword.Code, rec.Code, name)
+		assert.Equal(t, wrongPassword.Header(), rec.Header(), name)
+		assert.Equal(t, wrongPassword.Body.String(), rec.Body.String(), name)
 	}
+}
 
-	mockService.On("Login", input.Email, input.Password).Return(nil, errors.New("User not found"))
+func TestRefreshToken_Success(t *testing.T) {
+	user := &models.User{ID: 1, Email: "johndoe@gmail.com", Role: "admin"}
+	access := &models.UserAccess{Roles: []string{"admin"}, Permissions: []string{"users:read"}}
 
-	body, _ := json.Marshal(input)
-	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+	mockTokenGenerator := new(utils.MockTokenGenerator)
+	mockTokenGenerator.On("CreateToken", 1, "johndoe@gmail.com", access).Return("new-jwt-token", nil)
+
+	mockTokenService := new(MockTokenService)
+	mockTokenService.On("RotateRefreshToken", "old-refresh-token").Return(user, "new-refresh-token", nil)
+
+	mockRoleService := new(MockRoleService)
+	mockRoleService.On("UserAccess", user).Return(access, nil)
+
+	controller := controllers.NewUserController(new(MockUserService), mockTokenService, mockRoleService, new(MockMFAService), newLoginGuard(), mockTokenGenerator)
+
+	router := gin.Default()
+	router.Use(utils.ErrorHandler())
+	router.POST("/token/refresh", controller.RefreshToken)
+
+	body, _ := json.Marshal(models.RefreshTokenInput{RefreshToken: "old-refresh-token"})
+	req := httptest.NewRequest(http.MethodPost, "/token/refresh", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"message": "Token refreshed successfully", "token": "new-jwt-token", "refresh_token": "new-refresh-token"}`, rec.Body.String())
+
+	mockTokenService.AssertExpectations(t)
+	mockRoleService.AssertExpectations(t)
+	mockTokenGenerator.AssertExpectations(t)
+}
+
+func TestRefreshToken_Reused(t *testing.T) {
+	mockTokenService := new(MockTokenService)
+	mockTokenService.On("RotateRefreshToken", "used-refresh-token").Return(nil, "", models.ErrRefreshTokenReused)
+
+	controller := controllers.NewUserController(new(MockUserService), mockTokenService, new(MockRoleService), new(MockMFAService), newLoginGuard(), new(MockTokenGenerator))
+
+	router := gin.Default()
+	router.Use(utils.ErrorHandler())
+	router.POST("/token/refresh", controller.RefreshToken)
+
+	body, _ := json.Marshal(models.RefreshTokenInput{RefreshToken: "used-refresh-token"})
+	req := httptest.NewRequest(http.MethodPost, "/token/refresh", bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	rec := httptest.NewRecorder()
 
 	router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assertProblem(t, rec, models.CodeUnauthorized, "Refresh token has already been used")
+
+	mockTokenService.AssertExpectations(t)
+}
+
+func TestLogout_RevokesTokens(t *testing.T) {
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	claims := &utils.Claims{ID: 1, Email: "johndoe@gmail.com", Role: "user"}
+	claims.Id = "token-id"
+	claims.ExpiresAt = expiresAt.Unix()
+
+	mockTokenGenerator := new(utils.MockTokenGenerator)
+	mockTokenGenerator.On("ParseToken", "access-token").Return(claims, nil)
+
+	mockTokenService := new(MockTokenService)
+	mockTokenService.On("Logout", 1, "token-id", expiresAt, "refresh-token").Return(nil)
+
+	controller := controllers.NewUserController(new(MockUserService), mockTokenService, new(MockRoleService), new(MockMFAService), newLoginGuard(), mockTokenGenerator)
+
+	router := gin.Default()
+	router.Use(utils.ErrorHandler())
+	router.POST("/logout", utils.AuthMiddleware("user", mockTokenGenerator), controller.Logout)
+
+	body, _ := json.Marshal(models.LogoutInput{RefreshToken: "refresh-token"})
+	req := httptest.NewRequest(http.MethodPost, "/logout", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer access-token")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"message": "Logout successful"}`, rec.Body.String())
+
+	mockTokenService.AssertExpectations(t)
+	mockTokenGenerator.AssertExpectations(t)
+}
+
+func TestVerifyEmail_InvalidToken(t *testing.T) {
+	mockService := new(MockUserService)
+	controller := controllers.NewUserController(mockService, new(MockTokenService), new(MockRoleService), new(MockMFAService), newLoginGuard(), new(MockTokenGenerator))
+
+	router := gin.Default()
+	router.Use(utils.ErrorHandler())
+	router.POST("/verify-email", controller.VerifyEmail)
 
-	expected := `{"error": "User not found"}`
-	assert.JSONEq(t, expected, rec.Body.String())
+	mockService.On("VerifyEmail", "bad-token").Return(models.ErrInvalidVerificationToken)
+
+	body, _ := json.Marshal(models.VerifyEmailInput{Token: "bad-token"})
+	req := httptest.NewRequest(http.MethodPost, "/verify-email", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assertProblem(t, rec, models.CodeValidation, "Invalid or expired verification token")
 
 	mockService.AssertExpectations(t)
 }
+
+func TestLogin_MFARequired(t *testing.T) {
+	mockService := new(MockUserService)
+	mockService.On("Login", "johndoe@gmail.com", "johndoe123").
+		Return(&models.User{ID: 1, Email: "johndoe@gmail.com", Status: models.StatusActive, MFAEnabled: true}, nil)
+
+	mockTokenService := new(MockTokenService)
+	controller := controllers.NewUserController(mockService, mockTokenService, new(MockRoleService), new(MockMFAService), newLoginGuard(), new(MockTokenGenerator))
+
+	router := gin.Default()
+	router.Use(utils.ErrorHandler())
+	router.POST("/login", controller.Login)
+
+	body, _ := json.Marshal(models.LoginInput{Email: "johndoe@gmail.com", Password: "johndoe123"})
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"message": "Two-factor authentication required", "mfa_required": true, "mfa_token": "mocked-mfa-token"}`, rec.Body.String())
+
+	mockService.AssertExpectations(t)
+	mockTokenService.AssertNotCalled(t, "IssueRefreshToken", mock.Anything)
+}
+
+func TestLoginMFA_Success(t *testing.T) {
+	user := &models.User{ID: 1, Email: "johndoe@gmail.com", Status: models.StatusActive, MFAEnabled: true}
+	access := &models.UserAccess{Roles: []string{"user"}}
+
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	claims := &utils.Claims{ID: 1, Email: "johndoe@gmail.com", Purpose: utils.PurposeMFAPending}
+	claims.Id = "mfa-token-id"
+	claims.ExpiresAt = expiresAt.Unix()
+
+	mockTokenGenerator := new(utils.MockTokenGenerator)
+	mockTokenGenerator.On("ParseToken", "mfa-token").Return(claims, nil)
+	mockTokenGenerator.On("CreateToken", 1, "johndoe@gmail.com", access).Return("mocked-jwt-token", nil)
+
+	mockMFAService := new(MockMFAService)
+	mockMFAService.On("Verify", 1, "123456").Return(user, nil)
+
+	mockTokenService := new(MockTokenService)
+	mockTokenService.On("Logout", 1, "mfa-token-id", expiresAt, "").Return(nil)
+	mockTokenService.On("IssueRefreshToken", 1).Return("mocked-refresh-token", nil)
+
+	mockRoleService := new(MockRoleService)
+	mockRoleService.On("UserAccess", user).Return(access, nil)
+
+	controller := controllers.NewUserController(new(MockUserService), mockTokenService, mockRoleService, mockMFAService, newLoginGuard(), mockTokenGenerator)
+
+	router := gin.Default()
+	router.Use(utils.ErrorHandler())
+	router.POST("/login/mfa", controller.LoginMFA)
+
+	body, _ := json.Marshal(models.MFALoginInput{MFAToken: "mfa-token", Code: "123456"})
+	req := httptest.NewRequest(http.MethodPost, "/login/mfa", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "mocked-jwt-token", response["token"])
+	assert.Equal(t, "mocked-refresh-token", response["refresh_token"])
+
+	mockMFAService.AssertExpectations(t)
+	mockTokenService.AssertExpectations(t)
+	mockTokenGenerator.AssertExpectations(t)
+}
+
+func TestLoginMFA_RejectsAccessToken(t *testing.T) {
+	mockTokenGenerator := new(utils.MockTokenGenerator)
+	mockTokenGenerator.On("ParseToken", "access-token").Return(&utils.Claims{ID: 1, Email: "johndoe@gmail.com"}, nil)
+
+	mockMFAService := new(MockMFAService)
+	controller := controllers.NewUserController(new(MockUserService), new(MockTokenService), new(MockRoleService), mockMFAService, newLoginGuard(), mockTokenGenerator)
+
+	router := gin.Default()
+	router.Use(utils.ErrorHandler())
+	router.POST("/login/mfa", controller.LoginMFA)
+
+	body, _ := json.Marshal(models.MFALoginInput{MFAToken: "access-token", Code: "123456"})
+	req := httptest.NewRequest(http.MethodPost, "/login/mfa", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assertProblem(t, rec, models.CodeUnauthorized, "Invalid or expired MFA token")
+	mockMFAService.AssertNotCalled(t, "Verify", mock.Anything, mock.Anything)
+}
+
+func TestLogin_LocksAccountAfterRepeatedFailures(t *testing.T) {
+	mockService := new(MockUserService)
+	mockService.On("Login", "johndoe@gmail.com", "wrong").Return(nil, models.ErrInvalidCredentials)
+
+	store := repository.NewInMemoryLoginAttemptStore()
+	guard := services.NewLoginGuard(store, services.LoginGuardConfig{MaxFailures: 3})
+	controller := controllers.NewUserController(mockService, new(MockTokenService), new(MockRoleService), new(MockMFAService), guard, new(MockTokenGenerator))
+
+	router := gin.Default()
+	router.Use(utils.ErrorHandler())
+	router.POST("/login", controller.Login)
+
+	login := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.LoginInput{Email: "johndoe@gmail.com", Password: "wrong"})
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, login().Code)
+
+	// The next attempt falls inside the backoff delay.
+	rec := login()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	// Pretend the client waited out the backoff between failures.
+	for i := 0; i < 2; i++ {
+		_, err := store.RecordLoginFailure("account:johndoe@gmail.com", time.Now().Add(-time.Minute), time.Hour)
+		assert.NoError(t, err)
+	}
+
+	rec = login()
+	assert.Equal(t, http.StatusLocked, rec.Code)
+	assertProblem(t, rec, models.CodeLocked, "Account is temporarily locked after too many failed login attempts")
+	mockService.AssertNumberOfCalls(t, "Login", 1)
+}
@@ -1,8 +1,14 @@
 package utils
 
 import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"clean-arch/internal/logger"
+	"clean-arch/internal/metrics"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
 
@@ -11,86 +17,267 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-var Secret = []byte("your-secret-key")
+const DefaultAccessTokenTTL = 15 * time.Minute
+
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// ErrNoSigningKeys is returned by a RealTokenGenerator without Keys. There is
+// deliberately no built-in key to fall back to.
+var ErrNoSigningKeys = errors.New("no JWT signing keys configured")
+
+// PurposeMFAPending marks the token returned by the first login step of a
+// user with two-factor authentication. It is only accepted by the second
+// step, never by AuthMiddleware.
+const PurposeMFAPending = "mfa_pending"
 
 type MockTokenGenerator struct {
 	mock.Mock
 }
 
-func (m *MockTokenGenerator) CreateToken(id int, email, role string) (string, error) {
-	args := m.Called(id, email, role)
+func (m *MockTokenGenerator) CreateToken(id int, email string, access *models.UserAccess) (string, error) {
+	args := m.Called(id, email, access)
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockTokenGenerator) CreateMFAToken(id int, email string) (string, error) {
+	args := m.Called(id, email)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTokenGenerator) ParseToken(tokenString string) (*Claims, error) {
+	args := m.Called(tokenString)
+	if claims, ok := args.Get(0).(*Claims); ok {
+		return claims, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 type TokenGenerator interface {
-	CreateToken(id int, email, role string) (string, error)
+	CreateToken(id int, email string, access *models.UserAccess) (string, error)
+	CreateMFAToken(id int, email string) (string, error)
+	ParseToken(tokenString string) (*Claims, error)
 }
 
 type Claims struct {
 	ID    int    `json:"id"`
 	Email string `json:"email"`
-	Role  string `json:"role"`
+
+	// Role is the single role carried by tokens issued before RBAC. New
+	// tokens still set it, to admin or user, for clients that read it; it is
+	// empty for an account without any role.
+	Role        string   `json:"role"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	Purpose     string   `json:"purpose,omitempty"`
+
+	// IssuedAtMs is the issue time in milliseconds. The standard iat claim
+	// only has whole seconds, too coarse to tell a token issued right after
+	// a logout from everywhere from one issued right before it.
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
 	jwt.StandardClaims
 }
 
-type RealTokenGenerator struct{}
+// issuedAt falls back to the whole-second iat for tokens without iat_ms.
+func (c *Claims) issuedAt() time.Time {
+	if c.IssuedAtMs > 0 {
+		return time.UnixMilli(c.IssuedAtMs)
+	}
+	return time.Unix(c.IssuedAt, 0)
+}
+
+// legacy reports whether the token predates role and permission claims.
+func (c *Claims) legacy() bool {
+	return len(c.Roles) == 0
+}
+
+func (c *Claims) HasRole(role string) bool {
+	if c.legacy() {
+		return c.Role == role
+	}
+	return contains(c.Roles, role)
+}
+
+// HasPermission checks the permissions signed into the token. Legacy tokens
+// get the default permissions of their role.
+func (c *Claims) HasPermission(permission string) bool {
+	if c.legacy() {
+		return contains(models.DefaultRolePermissions[c.Role], permission)
+	}
+	return contains(c.Permissions, permission)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type RealTokenGenerator struct {
+	// AccessTTL is the lifetime of issued access tokens; DefaultAccessTokenTTL
+	// is used when it is zero. Clients renew them with a refresh token.
+	AccessTTL time.Duration
+
+	// Revocations, when set, is consulted by ParseToken to reject tokens
+	// that were logged out before they expired.
+	Revocations repository.RevocationStore
+
+	// Keys signs and verifies tokens; it is required.
+	Keys *KeyRing
+}
+
+func (r *RealTokenGenerator) CreateToken(id int, email string, access *models.UserAccess) (string, error) {
+	ttl := r.AccessTTL
+	if ttl <= 0 {
+		ttl = DefaultAccessTokenTTL
+	}
+
+	var role string
+	switch {
+	case contains(access.Roles, models.RoleAdmin):
+		role = models.RoleAdmin
+	case len(access.Roles) > 0:
+		role = models.RoleUser
+	}
+
+	return r.sign(Claims{
+		ID:          id,
+		Email:       email,
+		Role:        role,
+		Roles:       access.Roles,
+		Permissions: access.Permissions,
+	}, ttl)
+}
+
+// CreateMFAToken issues the short-lived token a user with two-factor
+// authentication exchanges, together with a code, for a session.
+func (r *RealTokenGenerator) CreateMFAToken(id int, email string) (string, error) {
+	return r.sign(Claims{
+		ID:      id,
+		Email:   email,
+		Purpose: PurposeMFAPending,
+	}, models.MFAPendingTokenTTL)
+}
 
-func (r *RealTokenGenerator) CreateToken(id int, email, role string) (string, error) {
-	claims := Claims{
-		ID:    id,
-		Email: email,
-		Role:  role,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 24).Unix(),
-			IssuedAt:  time.Now().Unix(),
-			Issuer:    "The Furnish Store",
-		},
+func (r *RealTokenGenerator) sign(claims Claims, ttl time.Duration) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(Secret)
+
+	now := time.Now()
+	claims.IssuedAtMs = now.UnixMilli()
+	claims.StandardClaims = jwt.StandardClaims{
+		Id:        jti,
+		ExpiresAt: now.Add(ttl).Unix(),
+		IssuedAt:  now.Unix(),
+		Issuer:    "The Furnish Store",
+	}
+	if r.Keys == nil {
+		return "", ErrNoSigningKeys
+	}
+	return r.Keys.Sign(claims)
+}
+
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// ParseToken verifies the signature and expiry of an access token and checks
+// it against the revocation store.
+func (r *RealTokenGenerator) ParseToken(tokenString string) (*Claims, error) {
+	if r.Keys == nil {
+		return nil, ErrNoSigningKeys
+	}
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, r.Keys.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+
+	if r.Revocations != nil {
+		revoked, err := r.Revocations.IsRevoked(claims.Id, claims.ID, claims.issuedAt())
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+
+	return claims, nil
 }
 
 func AuthMiddleware(requiredRole string, tokenGenerator TokenGenerator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			metrics.TokenValidationFailures.WithLabelValues(metrics.TokenMissing).Inc()
+			AbortWithError(c, models.NewError(models.CodeUnauthorized, "Authorization header required"))
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token missing"})
-			c.Abort()
+			metrics.TokenValidationFailures.WithLabelValues(metrics.TokenMissing).Inc()
+			AbortWithError(c, models.NewError(models.CodeUnauthorized, "Token missing"))
 			return
 		}
 
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		claims, err := tokenGenerator.ParseToken(tokenString)
+		reason := metrics.TokenInvalid
+		if err == nil && claims.Purpose != "" {
+			err = fmt.Errorf("token with purpose %q cannot be used for access", claims.Purpose)
+			reason = metrics.TokenWrongPurpose
+		}
+		if err != nil {
+			message := "Invalid or expired token"
+			if errors.Is(err, ErrTokenRevoked) {
+				reason = metrics.TokenRevoked
+				message = "Token has been revoked"
 			}
-			return Secret, nil
-		})
+			metrics.TokenValidationFailures.WithLabelValues(reason).Inc()
+			logger.FromContext(c.Request.Context()).WithFields(logger.Fields{"reason": reason}).Info("Rejected token", err.Error())
+			AbortWithError(c, models.Wrap(models.CodeUnauthorized, message, err))
+			return
+		}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+		// Admins may use every endpoint a regular user can.
+		if requiredRole != "" && !claims.HasRole(requiredRole) && !claims.HasRole(models.RoleAdmin) {
+			AbortWithError(c, models.ErrInsufficientPrivileges)
 			return
 		}
+		c.Set("claims", claims)
+		c.Set("id", claims.ID)
+		c.Set("email", claims.Email)
+		addLogFields(c, logger.Fields{"user_id": claims.ID})
 
-		if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-			if requiredRole != "" && claims.Role != requiredRole {
-				c.JSON(http.StatusForbidden, gin.H{"message": "Insufficient privileges"})
-				c.Abort()
-				return
-			}
-			c.Set("claims", claims)
-			c.Set("id", claims.ID)
-			c.Set("email", claims.Email)
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
+		c.Next()
+	}
+}
+
+// RequirePermission must run after AuthMiddleware. It rejects requests whose
+// token does not carry the given permission.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := GetClaims(c)
+		if err != nil {
+			AbortWithError(c, models.ErrUnauthorized)
+			return
+		}
+
+		if !claims.HasPermission(permission) {
+			AbortWithError(c, models.ErrInsufficientPrivileges)
 			return
 		}
 
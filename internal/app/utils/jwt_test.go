@@ -0,0 +1,168 @@
+package utils_test
+
+import (
+	"clean-arch/internal/app/utils"
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+// testSecret signs the tokens of these tests.
+var testSecret = []byte("a-test-secret-of-at-least-32-bytes")
+
+func testKeys(t *testing.T) *utils.KeyRing {
+	ring, err := utils.NewKeyRing(utils.NewHMACKey("", testSecret))
+	assert.NoError(t, err)
+	return ring
+}
+
+func TestAuthMiddleware_RejectsRevokedToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	revocations := repository.NewInMemoryRevocationStore()
+	tokenGenerator := &utils.RealTokenGenerator{AccessTTL: time.Minute, Revocations: revocations, Keys: testKeys(t)}
+
+	token, err := tokenGenerator.CreateToken(1, "johndoe@gmail.com", &models.UserAccess{Roles: []string{"user"}})
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/profile", utils.AuthMiddleware("user", tokenGenerator), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, request().Code)
+
+	claims, err := tokenGenerator.ParseToken(token)
+	assert.NoError(t, err)
+	assert.NoError(t, revocations.RevokeToken(claims.Id, claims.ID, time.Unix(claims.ExpiresAt, 0)))
+
+	rec := request()
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, utils.ProblemContentType, rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "urn:user-api:problem:unauthorized",
+		"title": "Unauthorized",
+		"status": 401,
+		"detail": "Token has been revoked",
+		"instance": "/profile",
+		"code": "unauthorized"
+	}`, rec.Body.String())
+}
+
+func TestParseToken_AcceptsTokenIssuedInTheSecondOfALogout(t *testing.T) {
+	revocations := repository.NewInMemoryRevocationStore()
+	tokenGenerator := &utils.RealTokenGenerator{AccessTTL: time.Minute, Revocations: revocations, Keys: testKeys(t)}
+	access := &models.UserAccess{Roles: []string{"user"}}
+
+	// Start right after a second boundary, so everything below happens
+	// within the same second.
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+
+	old, err := tokenGenerator.CreateToken(1, "johndoe@gmail.com", access)
+	assert.NoError(t, err)
+	time.Sleep(2 * time.Millisecond)
+	assert.NoError(t, revocations.RevokeUserTokens(1, time.Now(), time.Now().Add(time.Minute)))
+	time.Sleep(2 * time.Millisecond)
+	fresh, err := tokenGenerator.CreateToken(1, "johndoe@gmail.com", access)
+	assert.NoError(t, err)
+
+	_, err = tokenGenerator.ParseToken(old)
+	assert.ErrorIs(t, err, utils.ErrTokenRevoked)
+
+	claims, err := tokenGenerator.ParseToken(fresh)
+	assert.NoError(t, err)
+	oldClaims, _, _ := new(jwt.Parser).ParseUnverified(old, &utils.Claims{})
+	assert.Equal(t, oldClaims.Claims.(*utils.Claims).IssuedAt, claims.IssuedAt)
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokenGenerator := &utils.RealTokenGenerator{AccessTTL: time.Minute, Keys: testKeys(t)}
+
+	router := gin.New()
+	router.GET("/admin/users", utils.AuthMiddleware("", tokenGenerator), utils.RequirePermission(models.PermUsersRead), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	support, err := tokenGenerator.CreateToken(1, "support@gmail.com", &models.UserAccess{
+		Roles:       []string{"support", "user"},
+		Permissions: []string{models.PermUsersRead},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, request(support))
+
+	user, err := tokenGenerator.CreateToken(2, "johndoe@gmail.com", &models.UserAccess{Roles: []string{"user"}})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, request(user))
+}
+
+func TestRequirePermission_LegacyAdminToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Tokens issued before RBAC only carry a single role claim.
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, utils.Claims{
+		ID:    1,
+		Email: "admin@gmail.com",
+		Role:  models.RoleAdmin,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+	}).SignedString(testSecret)
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.POST("/admin/users/2/block", utils.AuthMiddleware("", &utils.RealTokenGenerator{Keys: testKeys(t)}), utils.RequirePermission(models.PermUsersBlock), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/2/block", nil)
+	req.Header.Set("Authorization", "Bearer "+legacy)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAuthMiddleware_RejectsAccountWithoutRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokenGenerator := &utils.RealTokenGenerator{AccessTTL: time.Minute, Keys: testKeys(t)}
+	router := gin.New()
+	router.GET("/profile", utils.AuthMiddleware("user", tokenGenerator), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	token, err := tokenGenerator.CreateToken(1, "johndoe@gmail.com", &models.UserAccess{})
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,260 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var ErrUnknownKeyID = errors.New("unknown signing key id")
+
+// Key is a JWT key identified by its kid. Private is only set for keys that
+// can sign; Public verifies. For HS256 both hold the shared secret.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private interface{}
+	Public  interface{}
+}
+
+// NewHMACKey wraps a shared secret for HS256.
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, Private: secret, Public: secret}
+}
+
+// LoadSigningKey reads a signing key from a file: a PEM private key for RS256
+// and EdDSA, or the raw secret for HS256.
+func LoadSigningKey(id, alg, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key %q: %w", id, err)
+	}
+
+	switch alg {
+	case AlgHS256:
+		secret := []byte(strings.TrimSpace(string(data)))
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("signing key %q: HS256 secrets must be at least 32 bytes", id)
+		}
+		return NewHMACKey(id, secret), nil
+	case AlgRS256:
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", id, err)
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, Private: private, Public: &private.PublicKey}, nil
+	case AlgEdDSA:
+		parsed, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", id, err)
+		}
+		private := parsed.(ed25519.PrivateKey)
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, Private: private, Public: private.Public()}, nil
+	default:
+		return nil, fmt.Errorf("signing key %q: unsupported algorithm %q", id, alg)
+	}
+}
+
+// LoadVerificationKey reads a PEM public key, typically of a key that has
+// been rotated out but whose tokens have not all expired yet.
+func LoadVerificationKey(id, alg, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read verification key %q: %w", id, err)
+	}
+
+	var public crypto.PublicKey
+	var method jwt.SigningMethod
+	switch alg {
+	case AlgRS256:
+		public, err = jwt.ParseRSAPublicKeyFromPEM(data)
+		method = jwt.SigningMethodRS256
+	case AlgEdDSA:
+		public, err = jwt.ParseEdPublicKeyFromPEM(data)
+		method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("verification key %q: unsupported algorithm %q", id, alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("verification key %q: %w", id, err)
+	}
+	return &Key{ID: id, Method: method, Public: public}, nil
+}
+
+// KeyRing signs with one key and verifies with any key it holds, so tokens
+// signed by the previous key stay valid during a rotation.
+type KeyRing struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+func NewKeyRing(signing *Key, verification ...*Key) (*KeyRing, error) {
+	if signing == nil || signing.Private == nil {
+		return nil, errors.New("key ring needs a signing key")
+	}
+
+	ring := &KeyRing{signing: signing, keys: make(map[string]*Key)}
+	for _, key := range append([]*Key{signing}, verification...) {
+		if _, exists := ring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+	return ring, nil
+}
+
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.Method, claims)
+	if k.signing.ID != "" {
+		token.Header["kid"] = k.signing.ID
+	}
+	return token.SignedString(k.signing.Private)
+}
+
+// Keyfunc picks the verification key by kid. The algorithm in the token
+// header must match the key, so an RSA public key is never accepted as an
+// HMAC secret. Tokens without a kid, issued before key rotation existed, are
+// checked against the key with an empty ID, if any.
+func (k *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS lists the asymmetric verification keys. HMAC secrets are never
+// published.
+func (k *KeyRing) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range k.keys {
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: AlgRS256,
+				N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: AlgEdDSA,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// JWKSHandler serves the key ring at /.well-known/jwks.json.
+func JWKSHandler(ring *KeyRing) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, ring.JWKS())
+	}
+}
+
+// KeyRingConfig describes the keys to load. VerificationKeys lists extra
+// public keys as comma-separated "kid:alg:path" entries.
+type KeyRingConfig struct {
+	Algorithm        string
+	KeyID            string
+	KeyFile          string
+	Secret           string
+	VerificationKeys string
+
+	// LegacySecret, when set, verifies HS256 tokens that carry no kid.
+	LegacySecret string
+}
+
+func BuildKeyRing(config KeyRingConfig) (*KeyRing, error) {
+	alg := config.Algorithm
+	if alg == "" {
+		alg = AlgHS256
+	}
+	kid := config.KeyID
+	if kid == "" {
+		kid = "primary"
+	}
+
+	var signing *Key
+	var err error
+	switch {
+	case config.KeyFile != "":
+		signing, err = LoadSigningKey(kid, alg, config.KeyFile)
+	case alg == AlgHS256 && config.Secret != "":
+		signing = NewHMACKey(kid, []byte(config.Secret))
+	default:
+		err = fmt.Errorf("no signing key configured for %s", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var verification []*Key
+	for _, entry := range strings.Split(config.VerificationKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid verification key %q: expected kid:alg:path", entry)
+		}
+		key, err := LoadVerificationKey(parts[0], parts[1], parts[2])
+		if err != nil {
+			return nil, err
+		}
+		verification = append(verification, key)
+	}
+
+	if config.LegacySecret != "" {
+		verification = append(verification, NewHMACKey("", []byte(config.LegacySecret)))
+	}
+
+	return NewKeyRing(signing, verification...)
+}
@@ -0,0 +1,105 @@
+package utils_test
+
+import (
+	"clean-arch/internal/app/utils"
+	"clean-arch/internal/core/models"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+func rsaKeyFiles(t *testing.T) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+	return writePEM(t, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+		writePEM(t, "rsa.pub", "PUBLIC KEY", public)
+}
+
+func TestKeyRing_RotatesKeys(t *testing.T) {
+	oldPrivate, oldPublic := rsaKeyFiles(t)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	assert.NoError(t, err)
+	newPrivate := writePEM(t, "ed25519.pem", "PRIVATE KEY", der)
+
+	oldRing, err := utils.BuildKeyRing(utils.KeyRingConfig{Algorithm: utils.AlgRS256, KeyID: "2024-01", KeyFile: oldPrivate})
+	assert.NoError(t, err)
+	oldToken, err := (&utils.RealTokenGenerator{Keys: oldRing}).CreateToken(1, "johndoe@gmail.com", &models.UserAccess{Roles: []string{"user"}})
+	assert.NoError(t, err)
+
+	newRing, err := utils.BuildKeyRing(utils.KeyRingConfig{
+		Algorithm:        utils.AlgEdDSA,
+		KeyID:            "2024-06",
+		KeyFile:          newPrivate,
+		VerificationKeys: "2024-01:RS256:" + oldPublic,
+	})
+	assert.NoError(t, err)
+	generator := &utils.RealTokenGenerator{Keys: newRing}
+
+	claims, err := generator.ParseToken(oldToken)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, claims.ID)
+
+	newToken, err := generator.CreateToken(2, "janedoe@gmail.com", &models.UserAccess{Roles: []string{"user"}})
+	assert.NoError(t, err)
+	parsed, _, err := new(jwt.Parser).ParseUnverified(newToken, &utils.Claims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-06", parsed.Header["kid"])
+	assert.Equal(t, "EdDSA", parsed.Header["alg"])
+
+	jwks := newRing.JWKS()
+	assert.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+	assert.Equal(t, "OKP", jwks.Keys[1].Kty)
+
+	_, err = (&utils.RealTokenGenerator{Keys: oldRing}).ParseToken(newToken)
+	var validationErr *jwt.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, utils.ErrUnknownKeyID, validationErr.Inner)
+}
+
+func TestKeyRing_RejectsAlgorithmConfusion(t *testing.T) {
+	private, _ := rsaKeyFiles(t)
+	ring, err := utils.BuildKeyRing(utils.KeyRingConfig{Algorithm: utils.AlgRS256, KeyID: "rsa", KeyFile: private})
+	assert.NoError(t, err)
+
+	// An HS256 token signed with the published RSA modulus as the secret.
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, utils.Claims{ID: 1})
+	token.Header["kid"] = "rsa"
+	forged, err := token.SignedString([]byte(ring.JWKS().Keys[0].N))
+	assert.NoError(t, err)
+
+	_, err = (&utils.RealTokenGenerator{Keys: ring}).ParseToken(forged)
+	assert.Error(t, err)
+}
+
+func TestKeyRing_HMACIsNotPublished(t *testing.T) {
+	ring, err := utils.BuildKeyRing(utils.KeyRingConfig{Secret: "a-long-enough-shared-secret-for-hs256", LegacySecret: "your-secret-key"})
+	assert.NoError(t, err)
+
+	assert.Empty(t, ring.JWKS().Keys)
+
+	// Tokens issued before kids were added are still accepted.
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, utils.Claims{ID: 1}).SignedString([]byte("your-secret-key"))
+	assert.NoError(t, err)
+	_, err = (&utils.RealTokenGenerator{Keys: ring}).ParseToken(legacy)
+	assert.NoError(t, err)
+}
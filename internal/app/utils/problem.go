@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/logger"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix namespaces the type URIs of problem responses. The
+// suffix is the error code, which clients should match on.
+const problemTypePrefix = "urn:user-api:problem:"
+
+// Problem is an RFC 7807 problem details response, extended with the
+// error code, the invalid fields and the request ID.
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      models.ErrorCode    `json:"code"`
+	Errors    []models.FieldError `json:"errors,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+}
+
+var statusByCode = map[models.ErrorCode]int{
+	models.CodeValidation:       http.StatusBadRequest,
+	models.CodeUnauthorized:     http.StatusUnauthorized,
+	models.CodeForbidden:        http.StatusForbidden,
+	models.CodeNotFound:         http.StatusNotFound,
+	models.CodeConflict:         http.StatusConflict,
+	models.CodePayloadTooLarge:  http.StatusRequestEntityTooLarge,
+	models.CodeUnsupportedMedia: http.StatusUnsupportedMediaType,
+	models.CodeLocked:           http.StatusLocked,
+	models.CodeRateLimited:      http.StatusTooManyRequests,
+	models.CodeInternal:         http.StatusInternalServerError,
+}
+
+// StatusOf returns the HTTP status for the code of err.
+func StatusOf(err error) int {
+	if status, ok := statusByCode[models.ErrorCodeOf(err)]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// NewProblem describes err for the client. Internal errors get a generic
+// detail so that causes such as database errors are not leaked.
+func NewProblem(c *gin.Context, err error) Problem {
+	code := models.ErrorCodeOf(err)
+	status := StatusOf(err)
+	problem := Problem{
+		Type:      problemTypePrefix + string(code),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    "Something went wrong",
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		RequestID: logger.RequestID(c.Request.Context()),
+	}
+
+	var domainErr *models.Error
+	if code != models.CodeInternal && errors.As(err, &domainErr) {
+		problem.Detail = domainErr.Message
+		problem.Errors = domainErr.Fields
+	}
+	return problem
+}
+
+// ErrorHandler turns the last error a handler recorded with c.Error into a
+// problem response, unless the handler already wrote one. Handlers report
+// failures with c.Error and return; this is the only place that maps errors
+// to statuses.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		writeProblem(c, c.Errors.Last().Err)
+	}
+}
+
+// AbortWithError records err, writes its problem response and stops the
+// chain. Middlewares use it so that they respond the same way whether or
+// not ErrorHandler is installed.
+func AbortWithError(c *gin.Context, err error) {
+	c.Error(err)
+	writeProblem(c, err)
+	c.Abort()
+}
+
+// writeProblem sets the content type first, since c.JSON keeps one that is
+// already set.
+func writeProblem(c *gin.Context, err error) {
+	problem := NewProblem(c, err)
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(problem.Status, problem)
+}
@@ -0,0 +1,114 @@
+package utils_test
+
+import (
+	"clean-arch/internal/app/utils"
+	"clean-arch/internal/core/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveError(err error) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(utils.ErrorHandler())
+	router.GET("/users/:id", func(c *gin.Context) {
+		c.Error(err)
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/7", nil))
+	return rec
+}
+
+func TestErrorHandler_MapsCodesToStatuses(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   models.ErrorCode
+	}{
+		{models.ErrInvalidID, http.StatusBadRequest, models.CodeValidation},
+		{models.ErrUserBlocked, http.StatusUnauthorized, models.CodeUnauthorized},
+		{models.ErrEmailNotVerified, http.StatusForbidden, models.CodeForbidden},
+		{models.ErrUserDoesNotExist, http.StatusNotFound, models.CodeNotFound},
+		{models.ErrUserAlreadyExists, http.StatusConflict, models.CodeConflict},
+		{models.ErrFileTooLarge, http.StatusRequestEntityTooLarge, models.CodePayloadTooLarge},
+		{models.ErrUnsupportedImage, http.StatusUnsupportedMediaType, models.CodeUnsupportedMedia},
+		{models.ErrAccountLocked, http.StatusLocked, models.CodeLocked},
+		{models.ErrTooManyRequests, http.StatusTooManyRequests, models.CodeRateLimited},
+		{models.Wrap(models.CodeUnauthorized, "Invalid credentials", models.ErrUserDoesNotExist), http.StatusUnauthorized, models.CodeUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.code), func(t *testing.T) {
+			rec := serveError(tt.err)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, utils.ProblemContentType, rec.Header().Get("Content-Type"))
+
+			var problem utils.Problem
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, tt.code, problem.Code)
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Equal(t, "/users/7", problem.Instance)
+		})
+	}
+}
+
+func TestErrorHandler_ReportsFieldErrors(t *testing.T) {
+	rec := serveError(models.ValidationFailed(
+		models.FieldError{Field: "email", Message: "Invalid email format"},
+		models.FieldError{Field: "password", Message: "This field is required"},
+	))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{
+		"type": "urn:user-api:problem:validation",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "Validation failed",
+		"instance": "/users/7",
+		"code": "validation",
+		"errors": [
+			{"field": "email", "message": "Invalid email format"},
+			{"field": "password", "message": "This field is required"}
+		]
+	}`, rec.Body.String())
+}
+
+func TestErrorHandler_HidesInternalErrors(t *testing.T) {
+	for _, err := range []error{
+		errors.New("pq: connection refused"),
+		models.Internal("failed to find user", errors.New("pq: connection refused")),
+	} {
+		rec := serveError(err)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "pq:")
+		assert.Contains(t, rec.Body.String(), `"detail":"Something went wrong"`)
+		assert.Contains(t, rec.Body.String(), `"code":"internal"`)
+	}
+}
+
+func TestErrorHandler_KeepsWrittenResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(utils.ErrorHandler())
+	router.GET("/", func(c *gin.Context) {
+		c.Error(errors.New("logged only"))
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"message": "ok"}`, rec.Body.String())
+}
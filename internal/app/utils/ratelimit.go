@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"clean-arch/internal/core/models"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKeyFunc picks the bucket a request is counted against.
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// KeyByIP gives every client IP its own bucket. Forwarded headers only count
+// when the request comes through one of the engine's trusted proxies.
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser gives every authenticated user their own bucket and must run
+// after AuthMiddleware. Anonymous requests fall back to the client IP.
+func KeyByUser(c *gin.Context) string {
+	if claims, err := GetClaims(c); err == nil {
+		return "user:" + strconv.Itoa(claims.ID)
+	}
+	return KeyByIP(c)
+}
+
+// KeyByRoute gives every client IP its own bucket on each route, so a policy
+// shared by several routes limits them separately.
+func KeyByRoute(c *gin.Context) string {
+	return "route:" + c.Request.Method + " " + c.FullPath() + " " + KeyByIP(c)
+}
+
+// RateLimitPolicy is a token bucket: it holds up to Burst requests and
+// refills at Rate requests per Per.
+type RateLimitPolicy struct {
+	Name  string
+	Rate  int
+	Per   time.Duration
+	Burst int
+	Key   RateLimitKeyFunc
+}
+
+// ParseRateLimit reads a policy written as "rate/period" or
+// "rate/period,burst", e.g. "5/1m" or "100/1h,20". The burst defaults to the
+// rate.
+func ParseRateLimit(name, spec string, key RateLimitKeyFunc) (RateLimitPolicy, error) {
+	policy := RateLimitPolicy{Name: name, Key: key}
+
+	limit, burst, hasBurst := strings.Cut(spec, ",")
+	rate, period, ok := strings.Cut(limit, "/")
+	if !ok {
+		return policy, fmt.Errorf("invalid rate limit %q: expected rate/period", spec)
+	}
+
+	var err error
+	if policy.Rate, err = strconv.Atoi(strings.TrimSpace(rate)); err != nil || policy.Rate <= 0 {
+		return policy, fmt.Errorf("invalid rate limit %q: rate must be a positive number", spec)
+	}
+	if policy.Per, err = time.ParseDuration(strings.TrimSpace(period)); err != nil || policy.Per <= 0 {
+		return policy, fmt.Errorf("invalid rate limit %q: period must be a positive duration", spec)
+	}
+
+	policy.Burst = policy.Rate
+	if hasBurst {
+		if policy.Burst, err = strconv.Atoi(strings.TrimSpace(burst)); err != nil || policy.Burst <= 0 {
+			return policy, fmt.Errorf("invalid rate limit %q: burst must be a positive number", spec)
+		}
+	}
+	return policy, nil
+}
+
+// RateLimitResult describes the state of a bucket after a request was
+// counted against it.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// RateLimitStore holds the buckets. InMemoryRateLimitStore keeps them per
+// process; a shared implementation lets several replicas enforce one limit.
+type RateLimitStore interface {
+	Allow(key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimit rejects requests over the policy with a rate_limited problem and reports the bucket
+// state in X-RateLimit-* headers. If the store fails the request is let
+// through, so an outage of a shared store does not take the API down.
+func RateLimit(store RateLimitStore, policy RateLimitPolicy) gin.HandlerFunc {
+	if policy.Burst <= 0 {
+		policy.Burst = policy.Rate
+	}
+	if policy.Key == nil {
+		policy.Key = KeyByIP
+	}
+
+	return func(c *gin.Context) {
+		result, err := store.Allow(policy.Name+":"+policy.Key(c), policy, time.Now())
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			AbortWithError(c, models.ErrTooManyRequests)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+type bucket struct {
+	tokens    float64
+	capacity  float64
+	perSecond float64
+	updated   time.Time
+}
+
+// refill adds the tokens earned since the last update.
+func (b *bucket) refill(now time.Time) float64 {
+	return math.Min(b.capacity, b.tokens+now.Sub(b.updated).Seconds()*b.perSecond)
+}
+
+// InMemoryRateLimitStore is a process-local RateLimitStore, meant for tests
+// and single-instance setups.
+type InMemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewInMemoryRateLimitStore() *InMemoryRateLimitStore {
+	return &InMemoryRateLimitStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (s *InMemoryRateLimitStore) Allow(key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > time.Minute {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	b.capacity = float64(policy.Burst)
+	b.perSecond = float64(policy.Rate) / policy.Per.Seconds()
+
+	b.tokens = b.refill(now)
+	b.updated = now
+
+	result := RateLimitResult{Limit: policy.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / b.perSecond)
+	}
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = seconds((b.capacity - b.tokens) / b.perSecond)
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely; a new request would
+// recreate them in the same state.
+func (s *InMemoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if b.refill(now) >= b.capacity {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,135 @@
+package utils_test
+
+import (
+	"clean-arch/internal/app/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit_RejectsOverLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	policy, err := utils.ParseRateLimit("login", "2/1m", utils.KeyByIP)
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.POST("/login", utils.RateLimit(utils.NewInMemoryRateLimitStore(), policy), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request("10.0.0.1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Remaining"))
+
+	assert.Equal(t, http.StatusOK, request("10.0.0.1").Code)
+
+	rec = request("10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), `"code":"rate_limited"`)
+
+	assert.Equal(t, http.StatusOK, request("10.0.0.2").Code)
+}
+
+func TestRateLimit_KeyByIPIgnoresSpoofedHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	policy, err := utils.ParseRateLimit("login", "1/1m", utils.KeyByIP)
+	assert.NoError(t, err)
+
+	newRouter := func(trustedProxies []string) *gin.Engine {
+		router := gin.New()
+		assert.NoError(t, router.SetTrustedProxies(trustedProxies))
+		router.POST("/login", utils.RateLimit(utils.NewInMemoryRateLimitStore(), policy), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return router
+	}
+	request := func(router *gin.Engine, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.Header.Set("X-Real-IP", forwardedFor)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	router := newRouter(nil)
+	assert.Equal(t, http.StatusOK, request(router, "203.0.113.1"))
+	assert.Equal(t, http.StatusTooManyRequests, request(router, "203.0.113.2"))
+
+	router = newRouter([]string{"10.0.0.0/8"})
+	assert.Equal(t, http.StatusOK, request(router, "203.0.113.1"))
+	assert.Equal(t, http.StatusOK, request(router, "203.0.113.2"))
+	assert.Equal(t, http.StatusTooManyRequests, request(router, "203.0.113.1"))
+}
+
+func TestRateLimit_KeyByRouteSeparatesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	policy, err := utils.ParseRateLimit("login", "1/1m", utils.KeyByRoute)
+	assert.NoError(t, err)
+
+	limit := utils.RateLimit(utils.NewInMemoryRateLimitStore(), policy)
+	router := gin.New()
+	router.POST("/login", limit, func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/token/refresh", limit, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(path, ip string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("/login", "10.0.0.1"))
+	assert.Equal(t, http.StatusOK, request("/token/refresh", "10.0.0.1"))
+	assert.Equal(t, http.StatusOK, request("/login", "10.0.0.2"))
+	assert.Equal(t, http.StatusTooManyRequests, request("/login", "10.0.0.1"))
+}
+
+func TestInMemoryRateLimitStore_Refills(t *testing.T) {
+	store := utils.NewInMemoryRateLimitStore()
+	policy := utils.RateLimitPolicy{Name: "user", Rate: 1, Per: time.Second, Burst: 1}
+	now := time.Now()
+
+	result, _ := store.Allow("user:1", policy, now)
+	assert.True(t, result.Allowed)
+
+	result, _ = store.Allow("user:1", policy, now.Add(500*time.Millisecond))
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	result, _ = store.Allow("user:1", policy, now.Add(time.Second))
+	assert.True(t, result.Allowed)
+}
+
+func TestParseRateLimit(t *testing.T) {
+	policy, err := utils.ParseRateLimit("user", "100/1h,20", utils.KeyByUser)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, policy.Rate)
+	assert.Equal(t, time.Hour, policy.Per)
+	assert.Equal(t, 20, policy.Burst)
+
+	_, err = utils.ParseRateLimit("user", "100", utils.KeyByUser)
+	assert.Error(t, err)
+	_, err = utils.ParseRateLimit("user", "0/1m", utils.KeyByUser)
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"clean-arch/internal/logger"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern limits accepted request IDs to what is safe to echo in a
+// header and to log.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestLogger assigns every request an ID, reusing a valid X-Request-ID
+// from the client or proxy, and echoes it in the response. It stores a
+// logger carrying the ID in the request context for handlers to pick up
+// with logger.FromContext, and logs one entry per request once it is done.
+func RequestLogger(log logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+
+		ctx := logger.WithRequestID(c.Request.Context(), id)
+		ctx = logger.NewContext(ctx, log.WithContext(ctx))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		fields := logger.Fields{
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"route":      c.FullPath(),
+			"status":     c.Writer.Status(),
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip":  c.ClientIP(),
+			"bytes":      c.Writer.Size(),
+		}
+		if claims, err := GetClaims(c); err == nil {
+			fields["user_id"] = claims.ID
+		}
+		if len(c.Errors) > 0 {
+			fields["errors"] = c.Errors.Errors()
+		}
+
+		entry := logger.FromContext(c.Request.Context()).WithFields(fields)
+		if c.Writer.Status() >= 500 {
+			entry.Error("Request failed")
+		} else {
+			entry.Info("Request handled")
+		}
+	}
+}
+
+// addLogFields adds fields to the request-scoped logger, so that every later
+// log entry of the request carries them.
+func addLogFields(c *gin.Context, fields logger.Fields) {
+	ctx := c.Request.Context()
+	c.Request = c.Request.WithContext(logger.NewContext(ctx, logger.FromContext(ctx).WithFields(fields)))
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
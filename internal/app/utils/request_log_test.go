@@ -0,0 +1,113 @@
+package utils_test
+
+import (
+	"clean-arch/internal/app/utils"
+	"clean-arch/internal/logger"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type logEntry struct {
+	message string
+	fields  logger.Fields
+}
+
+// recordingLogger keeps every entry with the fields it was logged with.
+type recordingLogger struct {
+	mu      *sync.Mutex
+	entries *[]logEntry
+	fields  logger.Fields
+}
+
+func newRecordingLogger() *recordingLogger {
+	return &recordingLogger{mu: &sync.Mutex{}, entries: &[]logEntry{}, fields: logger.Fields{}}
+}
+
+func (r *recordingLogger) record(message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.entries = append(*r.entries, logEntry{message: message, fields: r.fields})
+}
+
+func (r *recordingLogger) Info(message string, args ...interface{})  { r.record(message) }
+func (r *recordingLogger) Error(message string, args ...interface{}) { r.record(message) }
+func (r *recordingLogger) Debug(message string, args ...interface{}) { r.record(message) }
+func (r *recordingLogger) Warn(message string, args ...interface{})  { r.record(message) }
+
+func (r *recordingLogger) WithFields(fields logger.Fields) logger.Logger {
+	merged := logger.Fields{}
+	for k, v := range r.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &recordingLogger{mu: r.mu, entries: r.entries, fields: merged}
+}
+
+func (r *recordingLogger) WithContext(ctx context.Context) logger.Logger {
+	return r.WithFields(logger.Fields{"request_id": logger.RequestID(ctx)})
+}
+
+func TestRequestLogger_AssignsAndPropagatesRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := newRecordingLogger()
+
+	var seen string
+	router := gin.New()
+	router.Use(utils.RequestLogger(log))
+	router.GET("/users/:id", func(c *gin.Context) {
+		seen = logger.RequestID(c.Request.Context())
+		logger.FromContext(c.Request.Context()).Info("In handler")
+		c.Status(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/7", nil))
+
+	id := rec.Header().Get(utils.RequestIDHeader)
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, seen)
+
+	entries := *log.entries
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "In handler", entries[0].message)
+	assert.Equal(t, id, entries[0].fields["request_id"])
+
+	access := entries[1].fields
+	assert.Equal(t, id, access["request_id"])
+	assert.Equal(t, http.MethodGet, access["method"])
+	assert.Equal(t, "/users/7", access["path"])
+	assert.Equal(t, "/users/:id", access["route"])
+	assert.Equal(t, http.StatusNoContent, access["status"])
+	assert.Contains(t, access, "latency_ms")
+	assert.Contains(t, access, "client_ip")
+}
+
+func TestRequestLogger_AcceptsValidIncomingRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(utils.RequestLogger(newRecordingLogger()))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(id string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(utils.RequestIDHeader, id)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Header().Get(utils.RequestIDHeader)
+	}
+
+	assert.Equal(t, "edge-42.abc", request("edge-42.abc"))
+
+	replaced := request("bad id\r\nwith: injection")
+	assert.NotEqual(t, "bad id\r\nwith: injection", replaced)
+	assert.Len(t, replaced, 32)
+}
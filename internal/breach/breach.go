@@ -0,0 +1,96 @@
+// Package breach checks passwords against a local list of SHA-1 hashes of
+// breached passwords, such as a Pwned Passwords download.
+//
+// The list is indexed the way the Pwned Passwords range API answers
+// k-anonymity queries: by the first five hex digits of the hash, with only
+// the remaining suffixes compared. A lookup never needs the full list, so
+// the same index can later be filled from the range API instead.
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// PrefixLength is the number of hex digits of the hash that select a range.
+const PrefixLength = 5
+
+// List holds breached password hashes grouped into ranges by prefix.
+type List struct {
+	ranges map[string][]string
+}
+
+// Load reads a list file. See Parse for the format.
+func Load(path string) (*List, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer f.Close()
+
+	list, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return list, nil
+}
+
+// Parse reads one SHA-1 hash in hex per line, optionally followed by a colon
+// and a count as in the Pwned Passwords downloads. Blank lines and lines
+// starting with # are skipped.
+func Parse(r io.Reader) (*List, error) {
+	list := &List{ranges: make(map[string][]string)}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(text, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("line %d: not a SHA-1 hash", line)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("line %d: not a SHA-1 hash", line)
+		}
+		prefix, suffix := hash[:PrefixLength], hash[PrefixLength:]
+		list.ranges[prefix] = append(list.ranges[prefix], suffix)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, suffixes := range list.ranges {
+		sort.Strings(suffixes)
+	}
+	return list, nil
+}
+
+// Range returns the sorted hash suffixes that start with prefix.
+func (l *List) Range(prefix string) []string {
+	return l.ranges[strings.ToUpper(prefix)]
+}
+
+// Contains reports whether password is in the list.
+func (l *List) Contains(password string) bool {
+	prefix, suffix := Hash(password)
+	suffixes := l.Range(prefix)
+	i := sort.SearchStrings(suffixes, suffix)
+	return i < len(suffixes) && suffixes[i] == suffix
+}
+
+// Hash splits the upper-case hex SHA-1 of password into its range prefix
+// and suffix.
+func Hash(password string) (prefix, suffix string) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return hash[:PrefixLength], hash[PrefixLength:]
+}
@@ -0,0 +1,59 @@
+package breach_test
+
+import (
+	"clean-arch/internal/breach"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// SHA-1 of "password" and "letmein".
+const (
+	passwordHash = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+	letmeinHash  = "B7A875FC1EA228B9061041B7CEC4BD3C52AB3CE3"
+)
+
+func TestParse_MatchesListedPasswords(t *testing.T) {
+	list, err := breach.Parse(strings.NewReader("# Pwned Passwords sample\n" +
+		passwordHash + ":9545824\n\n" +
+		strings.ToLower(letmeinHash) + "\n"))
+
+	assert.NoError(t, err)
+	assert.True(t, list.Contains("password"))
+	assert.True(t, list.Contains("letmein"))
+	assert.False(t, list.Contains("Password"))
+	assert.False(t, list.Contains("correct horse battery staple"))
+}
+
+func TestParse_RejectsInvalidLines(t *testing.T) {
+	_, err := breach.Parse(strings.NewReader(passwordHash + "\nnot-a-hash\n"))
+
+	assert.EqualError(t, err, "line 2: not a SHA-1 hash")
+}
+
+func TestList_RangeHoldsSuffixesOnly(t *testing.T) {
+	list, err := breach.Parse(strings.NewReader(passwordHash + "\n"))
+	assert.NoError(t, err)
+
+	prefix, suffix := breach.Hash("password")
+
+	assert.Equal(t, passwordHash[:breach.PrefixLength], prefix)
+	assert.Equal(t, []string{suffix}, list.Range(strings.ToLower(prefix)))
+	assert.Empty(t, list.Range("00000"))
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(letmeinHash+"\n"), 0o600))
+
+	list, err := breach.Load(path)
+
+	assert.NoError(t, err)
+	assert.True(t, list.Contains("letmein"))
+
+	_, err = breach.Load(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
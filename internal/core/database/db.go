@@ -2,45 +2,127 @@ package database
 
 import (
 	"clean-arch/internal/app/config"
-	"clean-arch/internal/core/models"
+	"clean-arch/internal/logger"
+	"context"
 	"fmt"
-	"log"
+	"net"
+	"strings"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func ConnectDatabase(env config.Env) *gorm.DB {
-	
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=Asia/Shanghai",
-		env.DBHOST,
-		env.DBUSER,
-		env.DBPASSWORD,
-		env.DBNAME,
-		env.DBPORT,
-		env.SSLMODE,
-	)
-
-	fmt.Println("DSN:", dsn)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// maxConnectBackoff caps the wait between connection attempts.
+const maxConnectBackoff = 30 * time.Second
+
+// ConnectDatabase opens the connection pool of the primary database,
+// retrying with exponential backoff while it is unreachable.
+func ConnectDatabase(env config.Env, log logger.Logger) (*gorm.DB, error) {
+	return connect(env, env.DBHOST, env.DBPORT, log)
+}
+
+// ConnectReplica opens the pool of the read replica given as db_replica_host,
+// or returns nil if there is none.
+func ConnectReplica(env config.Env, log logger.Logger) (*gorm.DB, error) {
+	if env.DBREPLICAHOST == "" {
+		return nil, nil
+	}
+	host, port, err := net.SplitHostPort(env.DBREPLICAHOST)
 	if err != nil {
-		log.Fatal("Database connection failed", err)
-		return nil
+		host, port = env.DBREPLICAHOST, env.DBPORT
+	}
+	return connect(env, host, port, log)
+}
+
+func connect(env config.Env, host, port string, log logger.Logger) (*gorm.DB, error) {
+	attempts := env.DBCONNECTATTEMPTS
+	if attempts < 1 {
+		attempts = 1
 	}
+	backoff := env.DBCONNECTBACKOFF
 
-	err = AutoMigrate(db)
+	var db *gorm.DB
+	var err error
+	for attempt := 1; ; attempt++ {
+		db, err = gorm.Open(postgres.Open(DSN(env, host, port)), &gorm.Config{
+			Logger: logger.NewGormLogger(log.WithFields(logger.Fields{"db_host": host}), env.DBSLOWQUERYTHRESHOLD),
+		})
+		if err == nil {
+			break
+		}
+		if db != nil {
+			Close(db)
+		}
+		if attempt == attempts {
+			return nil, fmt.Errorf("failed to connect to database at %s after %d attempts: %w", host, attempts, err)
+		}
+
+		log.Warn("Database connection failed, retrying", host, err.Error(), backoff.String())
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatal("error in automigration", err)
-		return nil
+		return nil, err
 	}
-	return db
+	sqlDB.SetMaxOpenConns(env.DBMAXOPENCONNS)
+	sqlDB.SetMaxIdleConns(env.DBMAXIDLECONNS)
+	sqlDB.SetConnMaxLifetime(env.DBCONNMAXLIFETIME)
+	return db, nil
 }
 
-func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&models.User{},
-		&models.TempUser{},
-	)
+// DSN builds the connection string for host and port with the credentials
+// and session settings of env.
+func DSN(env config.Env, host, port string) string {
+	params := [][2]string{
+		{"host", host},
+		{"port", port},
+		{"user", env.DBUSER},
+		{"password", env.DBPASSWORD},
+		{"dbname", env.DBNAME},
+		{"sslmode", env.SSLMODE},
+		{"TimeZone", env.DBTIMEZONE},
+	}
+	if env.DBSTATEMENTTIMEOUT > 0 {
+		params = append(params, [2]string{"statement_timeout", fmt.Sprint(env.DBSTATEMENTTIMEOUT.Milliseconds())})
+	}
+
+	parts := make([]string, 0, len(params))
+	for _, param := range params {
+		if param[1] == "" {
+			continue
+		}
+		parts = append(parts, param[0]+"="+quoteDSNValue(param[1]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// quoteDSNValue quotes values that would otherwise break the key=value
+// format, such as passwords with spaces.
+func quoteDSNValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + replacer.Replace(value) + "'"
+}
+
+// Close closes the connection pool behind db.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Ping checks that the database is reachable.
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
 }
@@ -0,0 +1,51 @@
+package database_test
+
+import (
+	"clean-arch/internal/app/config"
+	"clean-arch/internal/core/database"
+	"clean-arch/internal/logger"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDSN(t *testing.T) {
+	env := config.Env{
+		DBUSER:             "postgres",
+		DBPASSWORD:         `it's a secret`,
+		DBNAME:             "users",
+		SSLMODE:            "disable",
+		DBTIMEZONE:         "UTC",
+		DBSTATEMENTTIMEOUT: 5 * time.Second,
+	}
+
+	assert.Equal(t,
+		`host=db port=5432 user=postgres password='it\'s a secret' dbname=users sslmode=disable TimeZone=UTC statement_timeout=5000`,
+		database.DSN(env, "db", "5432"))
+}
+
+func TestConnectDatabase_GivesUpAfterAttempts(t *testing.T) {
+	env := config.Env{
+		DBHOST:            "127.0.0.1",
+		DBPORT:            "1",
+		DBUSER:            "postgres",
+		DBNAME:            "users",
+		SSLMODE:           "disable",
+		DBTIMEZONE:        "UTC",
+		DBCONNECTATTEMPTS: 2,
+		DBCONNECTBACKOFF:  time.Millisecond,
+	}
+
+	db, err := database.ConnectDatabase(env, logger.NewLogrusLogger())
+
+	assert.Nil(t, db)
+	assert.ErrorContains(t, err, "after 2 attempts")
+}
+
+func TestConnectReplica_NoneConfigured(t *testing.T) {
+	db, err := database.ConnectReplica(config.Env{}, logger.NewLogrusLogger())
+
+	assert.NoError(t, err)
+	assert.Nil(t, db)
+}
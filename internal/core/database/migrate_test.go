@@ -0,0 +1,160 @@
+package database_test
+
+import (
+	"clean-arch/internal/core/database"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var testMigrations = fstest.MapFS{
+	"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id bigserial PRIMARY KEY);")},
+	"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	"0002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email text;")},
+	"0002_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+	"README.md":                  {Data: []byte("ignored")},
+}
+
+func newTestMigrator(t *testing.T) (*database.Migrator, sqlmock.Sqlmock, []database.Migration) {
+	migrations, err := database.LoadMigrations(testMigrations)
+	assert.NoError(t, err)
+
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return database.NewMigrator(db, migrations), mock, migrations
+}
+
+func expectLocked(mock sqlmock.Sqlmock, applied *sqlmock.Rows) {
+	mock.ExpectExec("SELECT pg_advisory_lock").WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, checksum, applied_at FROM schema_migrations").WillReturnRows(applied)
+}
+
+func TestMigrations_Embedded(t *testing.T) {
+	migrations, err := database.Migrations()
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, migrations)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Contains(t, migrations[0].Up, "CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email)")
+}
+
+func TestLoadMigrations_RequiresDownFile(t *testing.T) {
+	_, err := database.LoadMigrations(fstest.MapFS{
+		"0001_create_users.up.sql": {Data: []byte("CREATE TABLE users ();")},
+	})
+	assert.ErrorContains(t, err, "needs both an up and a down file")
+}
+
+func TestMigrator_UpAppliesPendingMigrations(t *testing.T) {
+	migrator, mock, migrations := newTestMigrator(t)
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+		AddRow(1, migrations[0].Checksum, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE users ADD COLUMN email text").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(int64(2), "add_email", migrations[1].Checksum, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, applied, 1)
+	assert.Equal(t, "add_email", applied[0].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_UpRejectsModifiedMigration(t *testing.T) {
+	migrator, mock, _ := newTestMigrator(t)
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+		AddRow(1, "checksum-of-an-older-version", time.Now()))
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up(context.Background())
+
+	assert.ErrorIs(t, err, database.ErrChecksumMismatch)
+	assert.Empty(t, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_UpRollsBackFailedMigration(t *testing.T) {
+	migrator, mock, _ := newTestMigrator(t)
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "checksum", "applied_at"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE users").WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err := migrator.Up(context.Background())
+
+	assert.ErrorContains(t, err, "failed to apply migration 1_create_users")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_DownRollsBackLatest(t *testing.T) {
+	migrator, mock, migrations := newTestMigrator(t)
+
+	expectLocked(mock, sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+		AddRow(1, migrations[0].Checksum, time.Now()).
+		AddRow(2, migrations[1].Checksum, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE users DROP COLUMN email").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rolledBack, err := migrator.Down(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, rolledBack, 1)
+	assert.Equal(t, int64(2), rolledBack[0].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Status(t *testing.T) {
+	migrator, mock, migrations := newTestMigrator(t)
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum", "applied_at"}).
+			AddRow(1, migrations[0].Checksum, time.Now()))
+
+	statuses, err := migrator.Status(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 2)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.False(t, statuses[0].Modified)
+	assert.Nil(t, statuses[1].AppliedAt)
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	for name, file := range testMigrations {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), file.Data, 0o644))
+	}
+
+	paths, err := database.CreateMigration(dir, "Add Avatar URL")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "0003_add_avatar_url.up.sql"),
+		filepath.Join(dir, "0003_add_avatar_url.down.sql"),
+	}, paths)
+
+	_, err = database.CreateMigration(dir, "drop; table")
+	assert.Error(t, err)
+}
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS password_histories;
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS user_token_revocations;
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS temp_users;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,140 @@
+-- Tables as previously created by GORM AutoMigrate. IF NOT EXISTS lets
+-- databases that were set up by AutoMigrate adopt this migration as is.
+
+CREATE TABLE IF NOT EXISTS users (
+    id                       bigserial PRIMARY KEY,
+    user_name                text,
+    email                    text,
+    password                 text,
+    phone_number             text,
+    status                   text,
+    role                     text DEFAULT 'user',
+    block_reason             text,
+    avatar_url               text,
+    created_at               timestamptz,
+    updated_at               timestamptz,
+    deleted_at               timestamptz,
+    pending_email            text,
+    pending_email_token_hash text,
+    mfa_enabled              boolean,
+    mfa_secret               text,
+    mfa_pending_secret       text,
+    mfa_last_used_step       bigint
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+-- One live account per address. Soft-deleted accounts keep their address
+-- until they are purged, so a new account may reuse it.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS temp_users (
+    id           bigserial PRIMARY KEY,
+    user_name    text,
+    address      text,
+    email        text,
+    password     text,
+    phone_number text,
+    token_hash   text,
+    expires_at   timestamptz,
+    created_at   timestamptz,
+    updated_at   timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_temp_users_email ON temp_users (email);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint,
+    family_id  text,
+    token_hash text,
+    expires_at timestamptz,
+    used_at    timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti        text PRIMARY KEY,
+    user_id    bigint,
+    expires_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_user_id ON revoked_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS user_token_revocations (
+    user_id        bigint PRIMARY KEY,
+    revoked_before timestamptz,
+    expires_at     timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_user_token_revocations_expires_at ON user_token_revocations (expires_at);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint,
+    token_hash text,
+    expires_at timestamptz,
+    used_at    timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS password_histories (
+    id            bigserial PRIMARY KEY,
+    user_id       bigint,
+    password_hash text,
+    created_at    timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_password_histories_user_id ON password_histories (user_id);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id          bigserial PRIMARY KEY,
+    name        text,
+    description text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_permissions_name ON permissions (name);
+
+CREATE TABLE IF NOT EXISTS roles (
+    id          bigserial PRIMARY KEY,
+    name        text,
+    description text,
+    created_at  timestamptz,
+    updated_at  timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_name ON roles (name);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id       bigint,
+    permission_id bigint,
+    PRIMARY KEY (role_id, permission_id),
+    CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_id) REFERENCES roles (id),
+    CONSTRAINT fk_role_permissions_permission FOREIGN KEY (permission_id) REFERENCES permissions (id)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id bigint,
+    role_id bigint,
+    PRIMARY KEY (user_id, role_id),
+    CONSTRAINT fk_user_roles_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_user_roles_role FOREIGN KEY (role_id) REFERENCES roles (id)
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint,
+    code_hash  text,
+    used_at    timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recovery_codes_code_hash ON recovery_codes (code_hash);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key             text PRIMARY KEY,
+    failures        bigint,
+    last_failure_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failure_at ON login_attempts (last_failure_at);
@@ -0,0 +1,3 @@
+-- assign_account_roles (down)
+-- The assignments are kept: they cannot be told apart from ones made later,
+-- and users.role still holds the role each was made from.
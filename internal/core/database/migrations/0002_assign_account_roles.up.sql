@@ -0,0 +1,15 @@
+-- Accounts used to hold the role stored in users.role on top of their
+-- assigned roles. Record it as an assignment instead, so that every role an
+-- account has is in user_roles and can be revoked. The built-in roles are
+-- created here if the server has not seeded them yet; the admin role gets its
+-- permissions when the server next starts.
+
+INSERT INTO roles (name, description, created_at, updated_at)
+VALUES ('user', 'Built-in role', now(), now()), ('admin', 'Built-in role', now(), now())
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO user_roles (user_id, role_id)
+SELECT users.id, roles.id
+FROM users
+JOIN roles ON roles.name = COALESCE(NULLIF(users.role, ''), 'user')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+const (
+	StatusActive  = "Active"
+	StatusBlocked = "Blocked"
+
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// UserFilter selects a page of users for the admin listing.
+type UserFilter struct {
+	Status   string
+	Role     string
+	Page     int
+	Limit    int
+	SortDesc bool
+}
+
+// Normalize clamps paging to sane defaults.
+func (f *UserFilter) Normalize() {
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.Limit < 1 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit > MaxPageSize {
+		f.Limit = MaxPageSize
+	}
+}
+
+type ListUsersQuery struct {
+	Status string `form:"status" validate:"omitempty,oneof=Active Blocked"`
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+	Sort   string `form:"sort" validate:"omitempty,oneof=created_at -created_at"`
+}
+
+type BlockUserInput struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+// AdminUserResponse is the admin view of a user; it never includes the
+// password hash.
+type AdminUserResponse struct {
+	ID          int       `json:"id"`
+	UserName    string    `json:"user_name"`
+	Email       string    `json:"email"`
+	PhoneNumber string    `json:"phone_number"`
+	Status      string    `json:"status"`
+	Role        string    `json:"role"`
+	BlockReason string    `json:"block_reason,omitempty"`
+	AvatarURL   string    `json:"avatar_url,omitempty"`
+	MFAEnabled  bool      `json:"mfa_enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func NewAdminUserResponse(user *User) AdminUserResponse {
+	return AdminUserResponse{
+		ID:          user.ID,
+		UserName:    user.UserName,
+		Email:       user.Email,
+		PhoneNumber: user.PhoneNumber,
+		Status:      user.Status,
+		Role:        user.Role,
+		BlockReason: user.BlockReason,
+		AvatarURL:   user.AvatarURL,
+		MFAEnabled:  user.MFAEnabled,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+	}
+}
@@ -0,0 +1,80 @@
+package models
+
+import "errors"
+
+// ErrorCode classifies an Error. The codes are part of the API: clients
+// receive them in the "code" member of problem responses.
+type ErrorCode string
+
+const (
+	CodeValidation       ErrorCode = "validation"
+	CodeUnauthorized     ErrorCode = "unauthorized"
+	CodeForbidden        ErrorCode = "forbidden"
+	CodeNotFound         ErrorCode = "not_found"
+	CodeConflict         ErrorCode = "conflict"
+	CodePayloadTooLarge  ErrorCode = "payload_too_large"
+	CodeUnsupportedMedia ErrorCode = "unsupported_media_type"
+	CodeLocked           ErrorCode = "locked"
+	CodeRateLimited      ErrorCode = "rate_limited"
+	CodeInternal         ErrorCode = "internal"
+)
+
+// FieldError describes a problem with one input field. Rule names the
+// check that failed, such as required or password_min_length, so that
+// clients need not parse the message.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Rule    string `json:"rule,omitempty"`
+}
+
+// Error is a domain error. Message is safe to show to clients; the wrapped
+// Err, if any, carries the cause for logs and errors.Is.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap gives err a code and a client-facing message. errors.Is and
+// errors.As still see err.
+func Wrap(code ErrorCode, message string, err error) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// Internal wraps an unexpected failure, such as a database error, whose
+// details must not reach clients.
+func Internal(message string, err error) *Error {
+	return Wrap(CodeInternal, message, err)
+}
+
+// ValidationFailed reports invalid input field by field.
+func ValidationFailed(fields ...FieldError) *Error {
+	return &Error{Code: CodeValidation, Message: "Validation failed", Fields: fields}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCodeOf returns the code of the outermost Error in err's chain, or
+// CodeInternal if there is none.
+func ErrorCodeOf(err error) ErrorCode {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	return CodeInternal
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// LoginAttempt counts consecutive failed logins for a key, which is either an
+// account ("account:<email>") or a client IP ("ip:<address>").
+type LoginAttempt struct {
+	Key           string    `json:"key" gorm:"primaryKey"`
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at" gorm:"index"`
+}
+
+// LoginThrottledError wraps ErrAccountLocked or ErrTooManyLoginAttempts with
+// the time after which the client may try again.
+type LoginThrottledError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", e.Err.Error(), e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+const (
+	MFAIssuer = "The Furnish Store"
+
+	// MFAPendingTokenTTL is how long the token returned by the first login
+	// step can be exchanged for a session.
+	MFAPendingTokenTTL = 5 * time.Minute
+
+	RecoveryCodeCount = 10
+)
+
+// RecoveryCode is a single-use code that can replace a TOTP code when the
+// user has lost their authenticator. Only its hash is stored.
+type RecoveryCode struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id" gorm:"index"`
+	CodeHash  string     `json:"-" gorm:"uniqueIndex"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// MFAEnrollment is returned when enrollment starts; the secret is shown once
+// so it can be entered manually if the QR code cannot be scanned.
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type MFACodeInput struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MFALoginInput struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type MFADisableInput struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type PasswordResetToken struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ForgotPasswordInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordInput struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,password"`
+	Reenter     string `json:"reenter" validate:"required,eqfield=NewPassword"`
+}
+
+// PasswordHistory keeps the hashes of previously used passwords so they
+// cannot be reused.
+type PasswordHistory struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id" gorm:"index"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
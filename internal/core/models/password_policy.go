@@ -0,0 +1,150 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Password policy rules, reported in PasswordViolation.Rule.
+const (
+	PasswordRuleMinLength = "min_length"
+	PasswordRuleMaxLength = "max_length"
+	PasswordRuleUpper     = "upper"
+	PasswordRuleLower     = "lower"
+	PasswordRuleDigit     = "digit"
+	PasswordRuleSymbol    = "symbol"
+	PasswordRuleIdentity  = "identity"
+	PasswordRuleStrength  = "strength"
+	PasswordRuleBreached  = "breached"
+)
+
+// MaxPasswordStrength is the best score PasswordStrength gives.
+const MaxPasswordStrength = 4
+
+// BreachedPasswords tells whether a password is known from a data breach.
+type BreachedPasswords interface {
+	Contains(password string) bool
+}
+
+// PasswordPolicy decides which passwords users may choose. Zero fields turn
+// their rule off, except MaxLength, which is always capped at
+// MaxPasswordLength since bcrypt ignores anything longer.
+type PasswordPolicy struct {
+	// MinLength counts characters; MaxLength counts bytes, as bcrypt does.
+	MinLength int
+	MaxLength int
+
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	// RejectIdentity refuses passwords that contain the user name, the
+	// email address or its local part.
+	RejectIdentity bool
+
+	// MinStrength is the lowest PasswordStrength score accepted, from 0 to
+	// MaxPasswordStrength.
+	MinStrength int
+
+	Breached BreachedPasswords
+}
+
+// PasswordViolation is a rule a password breaks. Param is the limit the
+// rule was checked against, if it has one.
+type PasswordViolation struct {
+	Rule  string
+	Param string
+}
+
+// DefaultPasswordPolicy only checks the length, as the service always has.
+// It matches the configuration defaults.
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{MinLength: MinPasswordLength, MaxLength: MaxPasswordLength}
+}
+
+// Check lists every rule password breaks. identities are the user name and
+// email of the account; they are only used when RejectIdentity is set, and
+// also count against the strength of the password.
+func (p *PasswordPolicy) Check(password string, identities ...string) []PasswordViolation {
+	var violations []PasswordViolation
+	add := func(rule string, param int) {
+		violations = append(violations, PasswordViolation{Rule: rule, Param: strconv.Itoa(param)})
+	}
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		add(PasswordRuleMinLength, p.MinLength)
+	}
+	if len(password) > p.maxLength() {
+		add(PasswordRuleMaxLength, p.maxLength())
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	for _, class := range []struct {
+		required, present bool
+		rule              string
+	}{
+		{p.RequireUpper, upper, PasswordRuleUpper},
+		{p.RequireLower, lower, PasswordRuleLower},
+		{p.RequireDigit, digit, PasswordRuleDigit},
+		{p.RequireSymbol, symbol, PasswordRuleSymbol},
+	} {
+		if class.required && !class.present {
+			violations = append(violations, PasswordViolation{Rule: class.rule})
+		}
+	}
+
+	if p.RejectIdentity && containsIdentity(password, identities) {
+		violations = append(violations, PasswordViolation{Rule: PasswordRuleIdentity})
+	}
+	if p.MinStrength > 0 && PasswordStrength(password, identities...) < p.MinStrength {
+		add(PasswordRuleStrength, p.MinStrength)
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, PasswordViolation{Rule: PasswordRuleBreached})
+	}
+	return violations
+}
+
+func (p *PasswordPolicy) maxLength() int {
+	if p.MaxLength <= 0 || p.MaxLength > MaxPasswordLength {
+		return MaxPasswordLength
+	}
+	return p.MaxLength
+}
+
+// minIdentityLength keeps very short names from ruling out most passwords.
+const minIdentityLength = 3
+
+// containsIdentity reports whether password contains, ignoring case, one of
+// identities or the local part of an email among them.
+func containsIdentity(password string, identities []string) bool {
+	password = strings.ToLower(password)
+	for _, identity := range identities {
+		identity = strings.ToLower(strings.TrimSpace(identity))
+		candidates := []string{identity}
+		if local, _, ok := strings.Cut(identity, "@"); ok {
+			candidates = append(candidates, local)
+		}
+		for _, candidate := range candidates {
+			if len(candidate) >= minIdentityLength && strings.Contains(password, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
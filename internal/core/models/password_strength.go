@@ -0,0 +1,256 @@
+package models
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// commonPasswords are among the most used passwords and password words,
+// most common first. A match counts as one guess per rank.
+var commonPasswords = []string{
+	"password", "123456", "qwerty", "111111", "abc123", "letmein", "monkey",
+	"dragon", "iloveyou", "admin", "welcome", "login", "master", "sunshine",
+	"princess", "football", "baseball", "shadow", "superman", "trustno1",
+	"starwars", "passw0rd", "hello", "freedom", "whatever", "qazwsx",
+	"ninja", "mustang", "michael", "jordan", "hunter", "charlie", "secret",
+	"summer", "winter", "spring", "autumn", "love", "money", "batman",
+	"pokemon", "computer", "internet", "access", "flower", "cheese",
+	"soccer", "hockey", "killer", "pepper", "ginger", "thomas", "jessica",
+	"ashley", "daniel", "andrew", "matrix", "google", "default", "changeme",
+	"user", "test", "guest", "root", "pass",
+}
+
+// keyboardRows are the rows of a US QWERTY keyboard, for walks such as
+// "asdf" or "0987".
+var keyboardRows = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"}
+
+// leetSubstitutions undoes common character substitutions before
+// dictionary matching.
+var leetSubstitutions = strings.NewReplacer("0", "o", "1", "l", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i")
+
+// bruteforceCardinality is what one character that matches no pattern
+// costs, as in zxcvbn.
+const bruteforceCardinality = 10
+
+// PasswordStrength scores password from 0 to MaxPasswordStrength like
+// zxcvbn: it estimates how many guesses an attacker who tries common
+// passwords, keyboard walks, sequences, repeats, years and the user's own
+// details first would need, and maps that to a score. A score of 3 or more
+// is a reasonable minimum for user accounts.
+func PasswordStrength(password string, userInputs ...string) int {
+	return strengthScore(passwordGuessesLog10(password, userInputs))
+}
+
+// strengthScore uses the zxcvbn thresholds: fewer than 10^3 guesses is
+// too guessable, 10^10 or more is very unguessable.
+func strengthScore(guessesLog10 float64) int {
+	for score, limit := range []float64{3, 6, 8, 10} {
+		if guessesLog10 < limit {
+			return score
+		}
+	}
+	return MaxPasswordStrength
+}
+
+// passwordGuessesLog10 finds the cheapest way to build password out of
+// pattern matches and brute-forced characters and returns its guess count
+// as a power of ten.
+func passwordGuessesLog10(password string, userInputs []string) float64 {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	// best[i] is the cheapest cost of runes[:i].
+	best := make([]float64, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i] = math.Inf(1)
+	}
+	matches := passwordMatches(runes, userInputs)
+	for i := 0; i < len(runes); i++ {
+		best[i+1] = math.Min(best[i+1], best[i]+math.Log10(bruteforceCardinality))
+		for _, m := range matches[i] {
+			best[m.end] = math.Min(best[m.end], best[i]+math.Log10(m.guesses))
+		}
+	}
+	return best[len(runes)]
+}
+
+type passwordMatch struct {
+	end     int
+	guesses float64
+}
+
+// passwordMatches lists the patterns found in runes, by start index.
+func passwordMatches(runes []rune, userInputs []string) map[int][]passwordMatch {
+	matches := make(map[int][]passwordMatch)
+	add := func(start, end int, guesses float64) {
+		matches[start] = append(matches[start], passwordMatch{end: end, guesses: math.Max(guesses, 1)})
+	}
+
+	dictionaryMatches(runes, userInputs, add)
+	repeatMatches(runes, add)
+	sequenceMatches(runes, add)
+	keyboardMatches(runes, add)
+	yearMatches(runes, add)
+	return matches
+}
+
+func dictionaryMatches(runes []rune, userInputs []string, add func(start, end int, guesses float64)) {
+	ranked := make(map[string]int)
+	for i, word := range commonPasswords {
+		ranked[word] = i + 1
+	}
+	// The user's own details are the first thing an attacker tries.
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		words := []string{input}
+		if local, _, ok := strings.Cut(input, "@"); ok {
+			words = append(words, local)
+		}
+		for _, word := range words {
+			if len([]rune(word)) >= minIdentityLength {
+				ranked[word] = 1
+			}
+		}
+	}
+
+	lower := []rune(strings.ToLower(string(runes)))
+	for i := range lower {
+		for j := i + minIdentityLength; j <= len(lower); j++ {
+			token := string(lower[i:j])
+			unleet := leetSubstitutions.Replace(token)
+			rank, ok := ranked[token]
+			leet := false
+			if !ok {
+				rank, ok = ranked[unleet]
+				leet = ok
+			}
+			if !ok {
+				continue
+			}
+			guesses := float64(rank) * uppercaseVariations(runes[i:j])
+			if leet {
+				guesses *= 2
+			}
+			add(i, j, guesses)
+		}
+	}
+}
+
+// uppercaseVariations is how many capitalisations an attacker tries before
+// the one in token: none for lower case, a couple for the common ones.
+func uppercaseVariations(token []rune) float64 {
+	var upper, lower int
+	for _, r := range token {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 1
+	case lower == 0 || (upper == 1 && unicode.IsUpper(token[0])):
+		return 2
+	default:
+		return math.Pow(2, float64(upper))
+	}
+}
+
+// repeatMatches finds a base string repeated back to back, such as "aaa"
+// or "abcabc".
+func repeatMatches(runes []rune, add func(start, end int, guesses float64)) {
+	for i := range runes {
+		for size := 1; i+2*size <= len(runes); size++ {
+			count := 1
+			for end := i + (count+1)*size; end <= len(runes) && string(runes[end-size:end]) == string(runes[i:i+size]); end += size {
+				count++
+			}
+			if count < 2 || (size == 1 && count < 3) {
+				continue
+			}
+			base := passwordGuessesLog10(string(runes[i:i+size]), nil)
+			add(i, i+count*size, math.Pow(10, base)*float64(count))
+		}
+	}
+}
+
+// sequenceMatches finds runs such as "abcd", "1234" or "9876".
+func sequenceMatches(runes []rune, add func(start, end int, guesses float64)) {
+	for i := 0; i < len(runes)-2; {
+		delta := runes[i+1] - runes[i]
+		end := i + 1
+		if delta == 1 || delta == -1 {
+			for end+1 < len(runes) && runes[end+1]-runes[end] == delta {
+				end++
+			}
+		}
+		if end-i+1 < 3 {
+			i++
+			continue
+		}
+
+		start := 26.0
+		switch {
+		case strings.ContainsRune("aAzZ019", runes[i]):
+			start = 4
+		case unicode.IsDigit(runes[i]):
+			start = 10
+		}
+		guesses := start * float64(end-i+1)
+		if delta < 0 {
+			guesses *= 2
+		}
+		add(i, end+1, guesses)
+		i = end
+	}
+}
+
+// keyboardMatches finds walks of four or more neighbouring keys along a
+// keyboard row, in either direction.
+func keyboardMatches(runes []rune, add func(start, end int, guesses float64)) {
+	lower := []rune(strings.ToLower(string(runes)))
+	for _, row := range keyboardRows {
+		for _, keys := range []string{row, reverse(row)} {
+			for i := range lower {
+				end := i
+				for end < len(lower) && strings.Contains(keys, string(lower[i:end+1])) {
+					end++
+				}
+				if end-i >= 4 {
+					add(i, end, 40*float64(end-i))
+				}
+			}
+		}
+	}
+}
+
+// yearMatches finds years from 1900 to 2099, which people often append.
+func yearMatches(runes []rune, add func(start, end int, guesses float64)) {
+	for i := 0; i+4 <= len(runes); i++ {
+		year := string(runes[i : i+4])
+		if (strings.HasPrefix(year, "19") || strings.HasPrefix(year, "20")) && isDigits(year) {
+			add(i, i+4, 200)
+		}
+	}
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+const (
+	PermUsersRead   = "users:read"
+	PermUsersBlock  = "users:block"
+	PermUsersLogout = "users:logout"
+	PermRolesManage = "roles:manage"
+)
+
+// Permissions lists every permission known to the application. They are
+// seeded into the database on startup.
+var Permissions = []Permission{
+	{Name: PermUsersRead, Description: "View user accounts"},
+	{Name: PermUsersBlock, Description: "Block and unblock user accounts"},
+	{Name: PermUsersLogout, Description: "End the sessions of other users"},
+	{Name: PermRolesManage, Description: "Manage roles and role assignments"},
+}
+
+// DefaultRolePermissions holds the built-in roles. They cannot be deleted and
+// the admin role always carries every permission. It also resolves the
+// permissions of tokens issued before roles were stored in the database.
+var DefaultRolePermissions = map[string][]string{
+	RoleUser:  {},
+	RoleAdmin: {PermUsersRead, PermUsersBlock, PermUsersLogout, PermRolesManage},
+}
+
+type Role struct {
+	ID          int          `json:"id"`
+	Name        string       `json:"name" gorm:"uniqueIndex"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+type Permission struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name" gorm:"uniqueIndex"`
+	Description string `json:"description"`
+}
+
+type CreateRoleInput struct {
+	Name        string   `json:"name" validate:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type RolePermissionsInput struct {
+	Permissions []string `json:"permissions"`
+}
+
+type AssignRoleInput struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// UserAccess is what gets signed into an access token: the names of the roles
+// assigned to a user and the union of their permissions.
+type UserAccess struct {
+	Roles       []string
+	Permissions []string
+}
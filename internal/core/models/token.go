@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type RefreshToken struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id" gorm:"index"`
+	FamilyID  string     `json:"family_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// RevokedToken blacklists a single access token by its jti until it expires.
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primaryKey"`
+	UserID    int       `json:"user_id" gorm:"index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserTokenRevocation invalidates every access token of a user issued before
+// RevokedBefore. It is kept until the last of those tokens has expired.
+type UserTokenRevocation struct {
+	UserID        int       `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	RevokedBefore time.Time `json:"revoked_before"`
+	ExpiresAt     time.Time `json:"expires_at" gorm:"index"`
+}
+
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -2,42 +2,82 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type User struct {
-	ID          int        `json:"id"`
-	UserName    string     `json:"user_name"`
-	Email       string     `json:"email"`
-	Password    string     `json:"password"`
-	PhoneNumber string     `json:"phone_number"`
-	Status      string     `json:"status"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	ID          int            `json:"id"`
+	UserName    string         `json:"user_name"`
+	Email       string         `json:"email"`
+	Password    string         `json:"password"`
+	PhoneNumber string         `json:"phone_number"`
+	Status      string         `json:"status"`
+	Role        string         `json:"role" gorm:"default:user"`
+	BlockReason string         `json:"block_reason,omitempty"`
+	AvatarURL   string         `json:"avatar_url"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	Roles       []Role         `json:"-" gorm:"many2many:user_roles"`
+
+	// PendingEmail is an address change awaiting verification; Email keeps
+	// the old address until it is confirmed.
+	PendingEmail          string `json:"-"`
+	PendingEmailTokenHash string `json:"-"`
+
+	// MFASecret and MFAPendingSecret are TOTP secrets encrypted at rest; the
+	// pending one is replaced or promoted when enrollment is confirmed.
+	MFAEnabled       bool   `json:"mfa_enabled"`
+	MFASecret        string `json:"-"`
+	MFAPendingSecret string `json:"-"`
+	MFALastUsedStep  int64  `json:"-"`
 }
 
+// TempUser holds a registration until its email address has been verified.
 type TempUser struct {
+	ID          int    `json:"id"`
 	UserName    string `json:"username"`
 	Address     string
-	Email       string `json:"email"`
+	Email       string `json:"email" gorm:"uniqueIndex"`
 	Password    string
 	PhoneNumber string
+	TokenHash   string    `json:"-"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 type SignupInput struct {
-	UserName    string `json:"user_name" validate:"required,min=3,max=16,alphanum"`
+	UserName    string `json:"user_name" validate:"required,min=3,max=16,username"`
 	Email       string `json:"email" validate:"required,email"`
-	PhoneNumber string `json:"phone_number" validate:"required,len=10,numeric"`
-	Password    string `json:"password" validate:"required,min=8,max=32"`
+	PhoneNumber string `json:"phone_number" validate:"required,phone"`
+	Password    string `json:"password" validate:"required,password"`
 }
 type LoginInput struct {
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=8,max=32"`
+	Password string `json:"password" validate:"required"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// UpdateProfileInput is a partial update: nil fields are left unchanged, but
+// fields that are sent must be valid.
+type UpdateProfileInput struct {
+	UserName    *string `json:"user_name" validate:"omitnil,min=3,max=16,username"`
+	PhoneNumber *string `json:"phone_number" validate:"omitnil,phone"`
+	Email       *string `json:"email" validate:"omitnil,email"`
 }
 
 type PasswordReset struct {
-	CurrentPassword string `json:"current_password"`
-	NewPassword     string `json:"new_password"`
-	Reenter         string `json:"reenter"`
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,password"`
+	Reenter         string `json:"reenter" validate:"required,eqfield=NewPassword"`
 }
 
 type UserProfileResponse struct {
@@ -45,4 +85,5 @@ type UserProfileResponse struct {
 	Email     string
 	PhnNumber string
 	Status    string
+	AvatarURL string
 }
@@ -1,34 +1,116 @@
 package models
 
-import "errors"
+import (
+	"time"
+)
 
 var (
-	ErrUserAlreadyExists = errors.New("User already exists")
-	ErrInvalidInput      = errors.New("Invalid input")
-	ErrUserBlocked       = errors.New("User is blocked")
-	ErrInvalidID         = errors.New("Invalid ID")
-	ErrUserDoesNotExist  = errors.New("user does not exists")
+	ErrUserAlreadyExists = NewError(CodeConflict, "User already exists")
+	ErrInvalidInput      = NewError(CodeValidation, "Invalid input")
+	ErrUserBlocked       = NewError(CodeUnauthorized, "User is blocked")
+	ErrAccountLocked     = NewError(CodeLocked, "Account is temporarily locked after too many failed login attempts")
+	ErrInvalidID         = NewError(CodeValidation, "Invalid ID")
+	ErrUserDoesNotExist  = NewError(CodeNotFound, "user does not exists")
+	ErrCannotModifySelf  = NewError(CodeValidation, "Admins cannot block or unblock themselves")
+
+	ErrTooManyLoginAttempts = NewError(CodeRateLimited, "Too many failed login attempts")
+	ErrTooManyRequests      = NewError(CodeRateLimited, "Too many requests")
+
+	ErrUnauthorized           = NewError(CodeUnauthorized, "Unauthorized")
+	ErrInsufficientPrivileges = NewError(CodeForbidden, "Insufficient privileges")
+
+	ErrEmailNotVerified         = NewError(CodeForbidden, "Email address is not verified")
+	ErrInvalidVerificationToken = NewError(CodeValidation, "Invalid or expired verification token")
+
+	ErrEmailAlreadyInUse = NewError(CodeConflict, "Email address is already in use")
+	ErrFileTooLarge      = NewError(CodePayloadTooLarge, "File is too large")
+	ErrImageTooLarge     = NewError(CodeValidation, "Image dimensions are too large")
+	ErrUnsupportedImage  = NewError(CodeUnsupportedMedia, "Unsupported image type")
+
+	ErrInvalidResetToken = NewError(CodeValidation, "Invalid or expired password reset token")
+	ErrIncorrectPassword = NewError(CodeUnauthorized, "Current password is incorrect")
+
+	// ErrInvalidCredentials is the only failure a login reports before the
+	// password is proven, so responses do not reveal which accounts exist.
+	ErrInvalidCredentials = NewError(CodeUnauthorized, "Invalid email or password")
+	ErrPasswordReused     = NewError(CodeValidation, "New password must differ from your recent passwords")
+
+	ErrInvalidRefreshToken = NewError(CodeUnauthorized, "Invalid or expired refresh token")
+	ErrRefreshTokenReused  = NewError(CodeUnauthorized, "Refresh token has already been used")
+
+	ErrRoleNotFound        = NewError(CodeNotFound, "Role not found")
+	ErrRoleAlreadyExists   = NewError(CodeConflict, "Role already exists")
+	ErrInvalidRoleName     = NewError(CodeValidation, "Role names may only contain lowercase letters, digits, '-' and '_'")
+	ErrUnknownPermission   = NewError(CodeValidation, "Unknown permission")
+	ErrBuiltinRole         = NewError(CodeValidation, "Built-in roles cannot be changed")
+	ErrCannotRevokeOwnRole = NewError(CodeValidation, "Admins cannot revoke their own roles")
+
+	ErrMFAAlreadyEnabled = NewError(CodeConflict, "Two-factor authentication is already enabled")
+	ErrMFANotEnabled     = NewError(CodeConflict, "Two-factor authentication is not enabled")
+	ErrMFANotEnrolling   = NewError(CodeConflict, "Start two-factor enrollment first")
+	ErrInvalidMFACode    = NewError(CodeValidation, "Invalid authentication code")
+	ErrInvalidMFAToken   = NewError(CodeUnauthorized, "Invalid or expired MFA token")
 )
 
 const (
 	MsgLoginSuccessful           = "Login successful"
 	MsgLogoutSuccessful          = "Logout successful"
+	MsgLogoutAllSuccessful       = "Logged out from all devices"
 	MsgSignupSuccessful          = "User signed up successfully!"
+	MsgTokenRefreshed            = "Token refreshed successfully"
 	MsgEmailVerifiedSuccessfully = "Email verified successfully"
 	MsgVerificationEmailResent   = "Verification email resent"
 	MsgPasswordResetEmailSent    = "Password reset email sent"
 	MsgPasswordResetSuccessfully = "Password reset successfully"
+	MsgPasswordChanged           = "Password changed successfully"
+	MsgAccountDeleted            = "Account scheduled for deletion"
 
 	MsgProfileUpdatedSuccessfully = "Profile updated successfully"
 	MsgProfilePictureUploaded     = "Profile picture uploaded successfully"
 
+	MsgUserBlocked   = "User blocked successfully"
+	MsgUserUnblocked = "User unblocked successfully"
+	MsgUserLoggedOut = "User logged out from all devices"
+	MsgUserUnlocked  = "User unlocked successfully"
+
+	MsgRoleDeleted  = "Role deleted successfully"
+	MsgRoleAssigned = "Role assigned successfully"
+	MsgRoleRevoked  = "Role revoked successfully"
+
+	MsgMFARequired = "Two-factor authentication required"
+	MsgMFAEnabled  = "Two-factor authentication enabled"
+	MsgMFADisabled = "Two-factor authentication disabled"
+
 	ErrRequiredFieldsEmpty = "Required fields cannot be empty"
-	ErrInvalidEmailFormat  = "Invalid email format"
 	ErrNegativeAge         = "Age must be positive"
-	ErrPasswordComplexity  = "Password must contain at least one uppercase letter, one lowercase letter, one number, and one special character"
-	ErrPasswordLength      = "Password must be between %d and %d characters"
-	ErrInvalidPhoneNumber  = "Invalid phone number format"
 
 	MinPasswordLength = 8
 	MaxPasswordLength = 72
+
+	VerificationTokenTTL  = 24 * time.Hour
+	PasswordResetTokenTTL = time.Hour
+
+	MaxProfilePictureSize      = 5 << 20
+	MaxProfilePictureDimension = 4096
+	AvatarSize                 = 256
+	AvatarThumbnailSize        = 64
+
+	// DefaultDeletionGracePeriod is how long a deleted account can still be
+	// restored by logging in before it is purged.
+	DefaultDeletionGracePeriod = 30 * 24 * time.Hour
+
+	PurgeModeDelete    = "delete"
+	PurgeModeAnonymize = "anonymize"
+
+	// PasswordHistoryLimit is how many previous passwords cannot be reused.
+	PasswordHistoryLimit = 5
+
+	// Login throttling defaults. Each failure doubles the wait before the
+	// next attempt, starting at LoginBackoffBase and capped at
+	// LoginBackoffMax; reaching the threshold locks the key for the window.
+	DefaultLoginMaxFailures   = 5
+	DefaultLoginIPMaxFailures = 50
+	DefaultLoginLockoutWindow = 15 * time.Minute
+	LoginBackoffBase          = time.Second
+	LoginBackoffMax           = 30 * time.Second
 )
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"clean-arch/internal/core/models"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptStore keeps failed-login counters. The Postgres implementation
+// is shared by all API replicas; the in-memory one is per process.
+type LoginAttemptStore interface {
+	// FindLoginAttempt returns nil without an error when the key has no
+	// recorded failures.
+	FindLoginAttempt(key string) (*models.LoginAttempt, error)
+
+	// RecordLoginFailure increments the counter of key and returns it. A
+	// counter whose last failure is older than window starts over at one.
+	RecordLoginFailure(key string, now time.Time, window time.Duration) (*models.LoginAttempt, error)
+
+	ResetLoginAttempts(key string) error
+	DeleteLoginAttemptsBefore(before time.Time) error
+}
+
+type LoginAttemptStorage struct {
+	DB *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) *LoginAttemptStorage {
+	return &LoginAttemptStorage{
+		DB: db,
+	}
+}
+
+func (repo *LoginAttemptStorage) FindLoginAttempt(key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	if err := repo.DB.Where("key = ?", key).First(&attempt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, models.Internal("failed to find login attempts", err)
+	}
+	return &attempt, nil
+}
+
+// RecordLoginFailure does the increment in a single upsert so concurrent
+// failures on different replicas are all counted.
+func (repo *LoginAttemptStorage) RecordLoginFailure(key string, now time.Time, window time.Duration) (*models.LoginAttempt, error) {
+	attempt := &models.LoginAttempt{Key: key, Failures: 1, LastFailureAt: now}
+	err := repo.DB.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":        gorm.Expr("CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END", now.Add(-window)),
+				"last_failure_at": now,
+			}),
+		},
+		clause.Returning{},
+	).Create(attempt).Error
+	if err != nil {
+		return nil, models.Internal("failed to record login failure", err)
+	}
+	return attempt, nil
+}
+
+func (repo *LoginAttemptStorage) ResetLoginAttempts(key string) error {
+	if err := repo.DB.Where("key = ?", key).Delete(&models.LoginAttempt{}).Error; err != nil {
+		return models.Internal("failed to reset login attempts", err)
+	}
+	return nil
+}
+
+func (repo *LoginAttemptStorage) DeleteLoginAttemptsBefore(before time.Time) error {
+	if err := repo.DB.Where("last_failure_at < ?", before).Delete(&models.LoginAttempt{}).Error; err != nil {
+		return models.Internal("failed to delete login attempts", err)
+	}
+	return nil
+}
+
+// InMemoryLoginAttemptStore is a process-local LoginAttemptStore, meant for
+// tests and single-instance setups.
+type InMemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+func NewInMemoryLoginAttemptStore() *InMemoryLoginAttemptStore {
+	return &InMemoryLoginAttemptStore{
+		attempts: make(map[string]models.LoginAttempt),
+	}
+}
+
+func (s *InMemoryLoginAttemptStore) FindLoginAttempt(key string) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (s *InMemoryLoginAttemptStore) RecordLoginFailure(key string, now time.Time, window time.Duration) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok || attempt.LastFailureAt.Before(now.Add(-window)) {
+		attempt = models.LoginAttempt{Key: key}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	s.attempts[key] = attempt
+	return &attempt, nil
+}
+
+func (s *InMemoryLoginAttemptStore) ResetLoginAttempts(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *InMemoryLoginAttemptStore) DeleteLoginAttemptsBefore(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, attempt := range s.attempts {
+		if attempt.LastFailureAt.Before(before) {
+			delete(s.attempts, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"clean-arch/internal/core/models"
+
+	"gorm.io/gorm"
+)
+
+type PasswordHistoryStorage struct {
+	DB *gorm.DB
+}
+
+type PasswordHistoryRepository interface {
+	AddPasswordHistory(*models.PasswordHistory) error
+	FindRecentPasswordHashes(userID int, limit int) ([]string, error)
+}
+
+func NewPasswordHistoryRepository(db *gorm.DB) *PasswordHistoryStorage {
+	return &PasswordHistoryStorage{
+		DB: db,
+	}
+}
+
+func (repo *PasswordHistoryStorage) AddPasswordHistory(entry *models.PasswordHistory) error {
+	if err := repo.DB.Create(entry).Error; err != nil {
+		return models.Internal("failed to add password history", err)
+	}
+	return nil
+}
+
+func (repo *PasswordHistoryStorage) FindRecentPasswordHashes(userID int, limit int) ([]string, error) {
+	var hashes []string
+	err := repo.DB.Model(&models.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Pluck("password_hash", &hashes).Error
+	if err != nil {
+		return nil, models.Internal("failed to find password history", err)
+	}
+	return hashes, nil
+}
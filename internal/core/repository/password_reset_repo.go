@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"clean-arch/internal/core/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrPasswordResetNotFound = models.NewError(models.CodeNotFound, "password reset not found")
+
+type PasswordResetStorage struct {
+	DB *gorm.DB
+}
+
+type PasswordResetRepository interface {
+	CreatePasswordReset(*models.PasswordResetToken) error
+	FindPasswordResetByHash(string) (*models.PasswordResetToken, error)
+	MarkPasswordResetUsed(id int, usedAt time.Time) (bool, error)
+	InvalidateUserPasswordResets(userID int, usedAt time.Time) error
+}
+
+func NewPasswordResetRepository(db *gorm.DB) *PasswordResetStorage {
+	return &PasswordResetStorage{
+		DB: db,
+	}
+}
+
+func (repo *PasswordResetStorage) CreatePasswordReset(reset *models.PasswordResetToken) error {
+	if err := repo.DB.Create(reset).Error; err != nil {
+		return models.Internal("failed to create password reset", err)
+	}
+	return nil
+}
+
+func (repo *PasswordResetStorage) FindPasswordResetByHash(hash string) (*models.PasswordResetToken, error) {
+	var reset models.PasswordResetToken
+	if err := repo.DB.Where("token_hash = ?", hash).First(&reset).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPasswordResetNotFound
+		}
+		return nil, models.Internal("failed to find password reset", err)
+	}
+	return &reset, nil
+}
+
+// MarkPasswordResetUsed consumes the token, reporting false if it was already
+// consumed by a concurrent request.
+func (repo *PasswordResetStorage) MarkPasswordResetUsed(id int, usedAt time.Time) (bool, error) {
+	result := repo.DB.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, models.Internal("failed to mark password reset used", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (repo *PasswordResetStorage) InvalidateUserPasswordResets(userID int, usedAt time.Time) error {
+	err := repo.DB.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", usedAt).Error
+	if err != nil {
+		return models.Internal("failed to invalidate password resets", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"clean-arch/internal/core/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrPendingUserNotFound = models.NewError(models.CodeNotFound, "pending user not found")
+
+type PendingUserStorage struct {
+	DB *gorm.DB
+}
+
+type PendingUserRepository interface {
+	SavePendingUser(*models.TempUser) error
+	FindPendingUserByEmail(string) (*models.TempUser, error)
+	PromotePendingUser(*models.TempUser) (*models.User, error)
+	DeleteExpiredPendingUsers(now time.Time) (int64, error)
+}
+
+func NewPendingUserRepository(db *gorm.DB) *PendingUserStorage {
+	return &PendingUserStorage{
+		DB: db,
+	}
+}
+
+// SavePendingUser stores a registration awaiting verification. A registration
+// already pending for the email is replaced as a whole: only the latest signup
+// can be verified, since its token hash supersedes the earlier links.
+func (repo *PendingUserStorage) SavePendingUser(pending *models.TempUser) error {
+	err := repo.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "email"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_name", "password", "phone_number", "token_hash", "expires_at", "updated_at"}),
+	}).Create(pending).Error
+	if err != nil {
+		return models.Internal("failed to save pending user", err)
+	}
+	return nil
+}
+
+func (repo *PendingUserStorage) FindPendingUserByEmail(email string) (*models.TempUser, error) {
+	var pending models.TempUser
+	if err := repo.DB.Where("email = ?", email).First(&pending).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPendingUserNotFound
+		}
+		return nil, models.Internal("failed to find pending user", err)
+	}
+	return &pending, nil
+}
+
+// PromotePendingUser turns a verified registration into a user with the
+// built-in user role and removes the pending record in a single transaction.
+func (repo *PendingUserStorage) PromotePendingUser(pending *models.TempUser) (*models.User, error) {
+	user := &models.User{
+		UserName:    pending.UserName,
+		Email:       pending.Email,
+		Password:    pending.Password,
+		PhoneNumber: pending.PhoneNumber,
+		Status:      models.StatusActive,
+		Role:        models.RoleUser,
+	}
+
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("INSERT INTO user_roles (user_id, role_id) SELECT ?, id FROM roles WHERE name = ?", user.ID, models.RoleUser).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.TempUser{}, pending.ID).Error
+	})
+	if err != nil {
+		return nil, models.Internal("failed to promote pending user", err)
+	}
+	return user, nil
+}
+
+// DeleteExpiredPendingUsers removes registrations whose verification token has
+// expired and reports how many were removed.
+func (repo *PendingUserStorage) DeleteExpiredPendingUsers(now time.Time) (int64, error) {
+	result := repo.DB.Where("expires_at <= ?", now).Delete(&models.TempUser{})
+	if result.Error != nil {
+		return 0, models.Internal("failed to delete expired pending users", result.Error)
+	}
+	return result.RowsAffected, nil
+}
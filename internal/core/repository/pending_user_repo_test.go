@@ -0,0 +1,87 @@
+package repository_test
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// mockDB opens a GORM connection whose queries are checked against mock.
+func mockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	assert.NoError(t, err)
+	return db, mock
+}
+
+func TestSavePendingUser_LatestRegistrationWins(t *testing.T) {
+	db, mock := mockDB(t)
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`ON CONFLICT ("email") DO UPDATE SET "user_name"="excluded"."user_name","password"="excluded"."password","phone_number"="excluded"."phone_number","token_hash"="excluded"."token_hash","expires_at"="excluded"."expires_at","updated_at"="excluded"."updated_at" RETURNING "id"`)).
+		WithArgs("owner", "", "owner@example.com", "owner-hash", "9876543210", "new-token", now, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	pending := &models.TempUser{
+		UserName:    "owner",
+		Email:       "owner@example.com",
+		Password:    "owner-hash",
+		PhoneNumber: "9876543210",
+		TokenHash:   "new-token",
+		ExpiresAt:   now,
+	}
+	assert.NoError(t, repository.NewPendingUserRepository(db).SavePendingUser(pending))
+
+	assert.Equal(t, 1, pending.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteExpiredPendingUsers(t *testing.T) {
+	db, mock := mockDB(t)
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "temp_users" WHERE expires_at <= $1`)).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	deleted, err := repository.NewPendingUserRepository(db).DeleteExpiredPendingUsers(now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPromotePendingUser_AssignsUserRole(t *testing.T) {
+	db, mock := mockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO user_roles (user_id, role_id) SELECT $1, id FROM roles WHERE name = $2`)).
+		WithArgs(5, models.RoleUser).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "temp_users" WHERE "temp_users"."id" = $1`)).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	user, err := repository.NewPendingUserRepository(db).PromotePendingUser(&models.TempUser{ID: 2, Email: "john@example.com"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, user.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"clean-arch/internal/core/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeStorage struct {
+	DB *gorm.DB
+}
+
+type RecoveryCodeRepository interface {
+	ReplaceRecoveryCodes(userID int, codes []models.RecoveryCode) error
+	UseRecoveryCode(userID int, hash string, usedAt time.Time) (bool, error)
+	DeleteRecoveryCodes(userID int) error
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) *RecoveryCodeStorage {
+	return &RecoveryCodeStorage{
+		DB: db,
+	}
+}
+
+// ReplaceRecoveryCodes drops every existing code of the user and stores the
+// new set in a single transaction.
+func (repo *RecoveryCodeStorage) ReplaceRecoveryCodes(userID int, codes []models.RecoveryCode) error {
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+	if err != nil {
+		return models.Internal("failed to replace recovery codes", err)
+	}
+	return nil
+}
+
+// UseRecoveryCode consumes the code, reporting false if it does not exist or
+// was already used.
+func (repo *RecoveryCodeStorage) UseRecoveryCode(userID int, hash string, usedAt time.Time) (bool, error) {
+	result := repo.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, models.Internal("failed to use recovery code", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (repo *RecoveryCodeStorage) DeleteRecoveryCodes(userID int) error {
+	if err := repo.DB.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return models.Internal("failed to delete recovery codes", err)
+	}
+	return nil
+}
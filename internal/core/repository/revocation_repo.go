@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"clean-arch/internal/core/models"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevocationStore records access tokens that must be rejected before their
+// natural expiry, either one by one (logout) or per user (logout everywhere).
+type RevocationStore interface {
+	RevokeToken(jti string, userID int, expiresAt time.Time) error
+	RevokeUserTokens(userID int, issuedBefore, expiresAt time.Time) error
+	IsRevoked(jti string, userID int, issuedAt time.Time) (bool, error)
+	DeleteExpired(now time.Time) error
+}
+
+type RevocationStorage struct {
+	DB *gorm.DB
+}
+
+func NewRevocationRepository(db *gorm.DB) *RevocationStorage {
+	return &RevocationStorage{
+		DB: db,
+	}
+}
+
+func (repo *RevocationStorage) RevokeToken(jti string, userID int, expiresAt time.Time) error {
+	revoked := &models.RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt}
+	if err := repo.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(revoked).Error; err != nil {
+		return models.Internal("failed to revoke token", err)
+	}
+	return nil
+}
+
+// RevokeUserTokens rejects every access token of the user issued before
+// issuedBefore. Tokens issued afterwards, even within the same second, stay
+// valid.
+func (repo *RevocationStorage) RevokeUserTokens(userID int, issuedBefore, expiresAt time.Time) error {
+	revocation := &models.UserTokenRevocation{UserID: userID, RevokedBefore: issuedBefore, ExpiresAt: expiresAt}
+	if err := repo.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(revocation).Error; err != nil {
+		return models.Internal("failed to revoke user tokens", err)
+	}
+	return nil
+}
+
+func (repo *RevocationStorage) IsRevoked(jti string, userID int, issuedAt time.Time) (bool, error) {
+	var count int64
+	if jti != "" {
+		if err := repo.DB.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+			return false, models.Internal("failed to check token revocation", err)
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+
+	err := repo.DB.Model(&models.UserTokenRevocation{}).
+		Where("user_id = ? AND revoked_before > ?", userID, issuedAt).
+		Count(&count).Error
+	if err != nil {
+		return false, models.Internal("failed to check token revocation", err)
+	}
+	return count > 0, nil
+}
+
+func (repo *RevocationStorage) DeleteExpired(now time.Time) error {
+	if err := repo.DB.Where("expires_at <= ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return models.Internal("failed to delete expired revocations", err)
+	}
+	if err := repo.DB.Where("expires_at <= ?", now).Delete(&models.UserTokenRevocation{}).Error; err != nil {
+		return models.Internal("failed to delete expired revocations", err)
+	}
+	return nil
+}
+
+// InMemoryRevocationStore is a process-local RevocationStore, meant for tests
+// and single-instance setups.
+type InMemoryRevocationStore struct {
+	mu     sync.RWMutex
+	tokens map[string]models.RevokedToken
+	users  map[int]models.UserTokenRevocation
+}
+
+func NewInMemoryRevocationStore() *InMemoryRevocationStore {
+	return &InMemoryRevocationStore{
+		tokens: make(map[string]models.RevokedToken),
+		users:  make(map[int]models.UserTokenRevocation),
+	}
+}
+
+func (s *InMemoryRevocationStore) RevokeToken(jti string, userID int, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[jti] = models.RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt, CreatedAt: time.Now()}
+	return nil
+}
+
+func (s *InMemoryRevocationStore) RevokeUserTokens(userID int, issuedBefore, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[userID] = models.UserTokenRevocation{UserID: userID, RevokedBefore: issuedBefore, ExpiresAt: expiresAt}
+	return nil
+}
+
+func (s *InMemoryRevocationStore) IsRevoked(jti string, userID int, issuedAt time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tokens[jti]; ok && jti != "" {
+		return true, nil
+	}
+	if revocation, ok := s.users[userID]; ok && revocation.RevokedBefore.After(issuedAt) {
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *InMemoryRevocationStore) DeleteExpired(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for jti, token := range s.tokens {
+		if !token.ExpiresAt.After(now) {
+			delete(s.tokens, jti)
+		}
+	}
+	for userID, revocation := range s.users {
+		if !revocation.ExpiresAt.After(now) {
+			delete(s.users, userID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package repository_test
+
+import (
+	"clean-arch/internal/core/repository"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevokeUserTokens_KeepsSubSecondCutoff(t *testing.T) {
+	db, mock := mockDB(t)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 750_000_000, time.UTC)
+	expiresAt := now.Add(time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "user_token_revocations"`)).
+		WithArgs(1, now, expiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repository.NewRevocationRepository(db).RevokeUserTokens(1, now, expiresAt))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsRevoked_ComparesIssueTimeStrictly(t *testing.T) {
+	db, mock := mockDB(t)
+
+	issuedAt := time.UnixMilli(1714564800250)
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE user_id = $1 AND revoked_before > $2`)).
+		WithArgs(1, issuedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	revoked, err := repository.NewRevocationRepository(db).IsRevoked("", 1, issuedAt)
+
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"clean-arch/internal/core/models"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrRoleNotFound = models.NewError(models.CodeNotFound, "role not found")
+
+type RoleStorage struct {
+	DB *gorm.DB
+}
+
+type RoleRepository interface {
+	ListRoles() ([]models.Role, error)
+	FindRoleByName(string) (*models.Role, error)
+	CreateRole(*models.Role) error
+	DeleteRole(*models.Role) error
+	SetRolePermissions(*models.Role, []models.Permission) error
+	ListPermissions() ([]models.Permission, error)
+	SavePermissions([]models.Permission) error
+	FindUserRoles(userID int) ([]models.Role, error)
+	AssignUserRole(userID int, role *models.Role) error
+	RemoveUserRole(userID int, role *models.Role) error
+	CountRoleUsers(name string) (int64, error)
+	FindRoleUserIDs(role *models.Role) ([]int, error)
+}
+
+func NewRoleRepository(db *gorm.DB) *RoleStorage {
+	return &RoleStorage{
+		DB: db,
+	}
+}
+
+func (repo *RoleStorage) ListRoles() ([]models.Role, error) {
+	var roles []models.Role
+	if err := repo.DB.Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		return nil, models.Internal("failed to list roles", err)
+	}
+	return roles, nil
+}
+
+func (repo *RoleStorage) FindRoleByName(name string) (*models.Role, error) {
+	var role models.Role
+	if err := repo.DB.Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, models.Internal("failed to find role", err)
+	}
+	return &role, nil
+}
+
+func (repo *RoleStorage) CreateRole(role *models.Role) error {
+	if err := repo.DB.Create(role).Error; err != nil {
+		return models.Internal("failed to create role", err)
+	}
+	return nil
+}
+
+// DeleteRole removes the role together with its permission and user
+// assignments.
+func (repo *RoleStorage) DeleteRole(role *models.Role) error {
+	err := repo.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM user_roles WHERE role_id = ?", role.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+	if err != nil {
+		return models.Internal("failed to delete role", err)
+	}
+	return nil
+}
+
+func (repo *RoleStorage) SetRolePermissions(role *models.Role, permissions []models.Permission) error {
+	if err := repo.DB.Model(role).Association("Permissions").Replace(permissions); err != nil {
+		return models.Internal("failed to update role permissions", err)
+	}
+	return nil
+}
+
+func (repo *RoleStorage) ListPermissions() ([]models.Permission, error) {
+	var permissions []models.Permission
+	if err := repo.DB.Order("name").Find(&permissions).Error; err != nil {
+		return nil, models.Internal("failed to list permissions", err)
+	}
+	return permissions, nil
+}
+
+// SavePermissions inserts the given permissions, updating the description of
+// those that already exist, and fills in their IDs.
+func (repo *RoleStorage) SavePermissions(permissions []models.Permission) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+	err := repo.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description"}),
+	}).Create(&permissions).Error
+	if err != nil {
+		return models.Internal("failed to save permissions", err)
+	}
+	return nil
+}
+
+func (repo *RoleStorage) FindUserRoles(userID int) ([]models.Role, error) {
+	var roles []models.Role
+	err := repo.DB.Preload("Permissions").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Find(&roles).Error
+	if err != nil {
+		return nil, models.Internal("failed to find user roles", err)
+	}
+	return roles, nil
+}
+
+func (repo *RoleStorage) AssignUserRole(userID int, role *models.Role) error {
+	if err := repo.DB.Model(&models.User{ID: userID}).Association("Roles").Append(role); err != nil {
+		return models.Internal("failed to assign role", err)
+	}
+	return nil
+}
+
+func (repo *RoleStorage) RemoveUserRole(userID int, role *models.Role) error {
+	if err := repo.DB.Model(&models.User{ID: userID}).Association("Roles").Delete(role); err != nil {
+		return models.Internal("failed to remove role", err)
+	}
+	return nil
+}
+
+// CountRoleUsers counts the accounts, deleted ones excepted, that have been
+// assigned the named role.
+func (repo *RoleStorage) CountRoleUsers(name string) (int64, error) {
+	var count int64
+	err := repo.DB.Table("user_roles").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Joins("JOIN users ON users.id = user_roles.user_id").
+		Where("roles.name = ? AND users.deleted_at IS NULL", name).
+		Count(&count).Error
+	if err != nil {
+		return 0, models.Internal("failed to count role users", err)
+	}
+	return count, nil
+}
+
+// FindRoleUserIDs returns the IDs of the users the role is assigned to.
+func (repo *RoleStorage) FindRoleUserIDs(role *models.Role) ([]int, error) {
+	var ids []int
+	if err := repo.DB.Table("user_roles").Where("role_id = ?", role.ID).Order("user_id").Pluck("user_id", &ids).Error; err != nil {
+		return nil, models.Internal("failed to find role users", err)
+	}
+	return ids, nil
+}
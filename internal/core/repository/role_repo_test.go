@@ -0,0 +1,24 @@
+package repository_test
+
+import (
+	"clean-arch/internal/core/repository"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountRoleUsers_CountsAssignments(t *testing.T) {
+	db, mock := mockDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_roles" JOIN roles ON roles.id = user_roles.role_id JOIN users ON users.id = user_roles.user_id WHERE roles.name = $1 AND users.deleted_at IS NULL`)).
+		WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	count, err := repository.NewRoleRepository(db).CountRoleUsers("admin")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"clean-arch/internal/core/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrRefreshTokenNotFound = models.NewError(models.CodeNotFound, "refresh token not found")
+
+type RefreshTokenStorage struct {
+	DB *gorm.DB
+}
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(*models.RefreshToken) error
+	FindRefreshTokenByHash(string) (*models.RefreshToken, error)
+	MarkRefreshTokenUsed(id int, usedAt time.Time) (bool, error)
+	RevokeRefreshTokenFamily(familyID string, revokedAt time.Time) error
+	RevokeUserRefreshTokens(userID int, revokedAt time.Time) error
+	DeleteExpiredRefreshTokens(now time.Time) error
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenStorage {
+	return &RefreshTokenStorage{
+		DB: db,
+	}
+}
+
+func (repo *RefreshTokenStorage) CreateRefreshToken(token *models.RefreshToken) error {
+	if err := repo.DB.Create(token).Error; err != nil {
+		return models.Internal("failed to create refresh token", err)
+	}
+
+	return nil
+}
+
+func (repo *RefreshTokenStorage) FindRefreshTokenByHash(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := repo.DB.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, models.Internal("failed to find refresh token", err)
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed flags the token as consumed. It reports false when the
+// token had already been used, so concurrent refreshes cannot both succeed.
+func (repo *RefreshTokenStorage) MarkRefreshTokenUsed(id int, usedAt time.Time) (bool, error) {
+	result := repo.DB.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, models.Internal("failed to mark refresh token used", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (repo *RefreshTokenStorage) RevokeRefreshTokenFamily(familyID string, revokedAt time.Time) error {
+	err := repo.DB.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", revokedAt).Error
+	if err != nil {
+		return models.Internal("failed to revoke refresh token family", err)
+	}
+	return nil
+}
+
+func (repo *RefreshTokenStorage) RevokeUserRefreshTokens(userID int, revokedAt time.Time) error {
+	err := repo.DB.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", revokedAt).Error
+	if err != nil {
+		return models.Internal("failed to revoke refresh tokens", err)
+	}
+	return nil
+}
+
+func (repo *RefreshTokenStorage) DeleteExpiredRefreshTokens(now time.Time) error {
+	if err := repo.DB.Where("expires_at <= ?", now).Delete(&models.RefreshToken{}).Error; err != nil {
+		return models.Internal("failed to delete expired refresh tokens", err)
+	}
+	return nil
+}
@@ -2,20 +2,33 @@ package repository
 
 import (
 	"clean-arch/internal/core/models"
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type UserStorage struct {
 	DB *gorm.DB
+	// Replica, when set, serves ListUsers. Single-user lookups feed updates
+	// and authentication, so they always read the primary.
+	Replica *gorm.DB
 }
 
 type UserRespository interface {
-	FindUserByEmail(string) (*models.User, error)
-	FindUserByID(int) (*models.User, error)
-	CreateUser(*models.User) error
-	// UpdateUser(*models.User) error
+	FindUserByEmail(context.Context, string) (*models.User, error)
+	FindUserByID(context.Context, int) (*models.User, error)
+	FindUserByPendingEmail(context.Context, string) (*models.User, error)
+	ListUsers(context.Context, models.UserFilter) ([]models.User, int64, error)
+	CreateUser(context.Context, *models.User) error
+	UpdateUser(ctx context.Context, user *models.User, columns ...string) error
+	DeleteUser(context.Context, int) error
+	FindDeletedUserByEmail(context.Context, string) (*models.User, error)
+	FindDeletedUserByID(context.Context, int) (*models.User, error)
+	RestoreUser(context.Context, int) error
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, anonymize bool) ([]models.User, error)
 }
 
 func NewUserRepository(db *gorm.DB) *UserStorage {
@@ -24,37 +37,194 @@ func NewUserRepository(db *gorm.DB) *UserStorage {
 	}
 }
 
-func (repo *UserStorage) CreateUser(user *models.User) error {
-	if err := repo.DB.Create(user).Error; err != nil {
-		return errors.New("failed to create user: " + err.Error())
+func (repo *UserStorage) CreateUser(ctx context.Context, user *models.User) error {
+	if err := repo.DB.WithContext(ctx).Create(user).Error; err != nil {
+		return models.Internal("failed to create user", err)
 	}
 
 	return nil
 }
 
-func (repo *UserStorage) FindUser(field string, value interface{}) (*models.User, error) {
+func (repo *UserStorage) FindUser(ctx context.Context, field string, value interface{}) (*models.User, error) {
 	var user models.User
-	if err := repo.DB.Where(field+" = ?", value).First(&user).Error; err != nil {
+	if err := repo.DB.WithContext(ctx).Where(field+" = ?", value).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, models.ErrUserDoesNotExist
 		}
-		return nil, errors.New("failed to find user: " + err.Error())
+		return nil, models.Internal("failed to find user", err)
 	}
 	return &user, nil
 }
 
-func (repo *UserStorage) FindUserByEmail(email string) (*models.User, error) {
-	return repo.FindUser("email", email)
+func (repo *UserStorage) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return repo.FindUser(ctx, "email", email)
 }
 
-func (repo *UserStorage) FindUserByID(userID int) (*models.User, error) {
-	return repo.FindUser("id", userID)
+func (repo *UserStorage) FindUserByID(ctx context.Context, userID int) (*models.User, error) {
+	return repo.FindUser(ctx, "id", userID)
 }
 
-// func (repo *UserStorage) UpdateUser(user *models.User) error {
-// 	if err := repo.DB.Save(user).Error; err != nil {
-// 		return errors.New("failed to update user:" + err.Error())
-// 	}
+func (repo *UserStorage) FindUserByPendingEmail(ctx context.Context, email string) (*models.User, error) {
+	return repo.FindUser(ctx, "pending_email", email)
+}
+
+// ListUsers reads from the replica when one is configured, so a change made
+// moments earlier may not be listed yet.
+func (repo *UserStorage) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int64, error) {
+	reader := repo.DB
+	if repo.Replica != nil {
+		reader = repo.Replica
+	}
+
+	query := reader.WithContext(ctx).Model(&models.User{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Role != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM user_roles JOIN roles ON roles.id = user_roles.role_id WHERE user_roles.user_id = users.id AND roles.name = ?)", filter.Role)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, models.Internal("failed to count users", err)
+	}
+
+	order := "created_at ASC"
+	if filter.SortDesc {
+		order = "created_at DESC"
+	}
+
+	var users []models.User
+	err := query.Order(order).Order("id").
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, models.Internal("failed to list users", err)
+	}
+	return users, total, nil
+}
+
+// UpdateUser writes the named columns of user and nothing else, so concurrent
+// changes to other columns, such as a block and a profile edit, do not
+// overwrite each other. Soft-deleted rows are updated too: the second login
+// step records the code it used before restoring the account.
+func (repo *UserStorage) UpdateUser(ctx context.Context, user *models.User, columns ...string) error {
+	if len(columns) == 0 {
+		return models.Internal("failed to update user", errors.New("no columns to update"))
+	}
+	if err := repo.DB.WithContext(ctx).Unscoped().Model(user).Select(columns).Updates(user).Error; err != nil {
+		return models.Internal("failed to update user", err)
+	}
+
+	return nil
+}
+
+// DeleteUser soft-deletes the user; GORM hides the row from regular queries
+// until it is restored or purged.
+func (repo *UserStorage) DeleteUser(ctx context.Context, userID int) error {
+	if err := repo.DB.WithContext(ctx).Delete(&models.User{}, userID).Error; err != nil {
+		return models.Internal("failed to delete user", err)
+	}
 
-// 	return nil
-// }
+	return nil
+}
+
+func (repo *UserStorage) FindDeletedUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := repo.DB.WithContext(ctx).Unscoped().
+		Where("email = ? AND deleted_at IS NOT NULL", email).
+		Order("deleted_at DESC").
+		First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrUserDoesNotExist
+		}
+		return nil, models.Internal("failed to find user", err)
+	}
+	return &user, nil
+}
+
+func (repo *UserStorage) FindDeletedUserByID(ctx context.Context, userID int) (*models.User, error) {
+	var user models.User
+	err := repo.DB.WithContext(ctx).Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", userID).
+		First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrUserDoesNotExist
+		}
+		return nil, models.Internal("failed to find user", err)
+	}
+	return &user, nil
+}
+
+func (repo *UserStorage) RestoreUser(ctx context.Context, userID int) error {
+	err := repo.DB.WithContext(ctx).Unscoped().Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("deleted_at", nil).Error
+	if err != nil {
+		return models.Internal("failed to restore user", err)
+	}
+
+	return nil
+}
+
+// PurgeDeletedUsers permanently removes accounts soft-deleted before the given
+// time. With anonymize set, the rows are kept but stripped of personal data;
+// otherwise they are hard-deleted together with their dependent records. It
+// returns the ID and avatar URL of every purged account, as they were before
+// the purge, so the caller can clean up stored files.
+func (repo *UserStorage) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, anonymize bool) ([]models.User, error) {
+	var users []models.User
+	err := repo.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Select("id", "avatar_url").
+			Where("deleted_at IS NOT NULL AND deleted_at < ? AND email NOT LIKE ?", deletedBefore, "deleted-%@deleted.invalid").
+			Find(&users).Error
+		if err != nil || len(users) == 0 {
+			return err
+		}
+
+		ids := make([]int, 0, len(users))
+		for _, user := range users {
+			ids = append(ids, user.ID)
+		}
+
+		for _, dependent := range []interface{}{&models.RefreshToken{}, &models.PasswordResetToken{}, &models.PasswordHistory{}, &models.RecoveryCode{}} {
+			if err := tx.Where("user_id IN ?", ids).Delete(dependent).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("DELETE FROM user_roles WHERE user_id IN ?", ids).Error; err != nil {
+			return err
+		}
+
+		if !anonymize {
+			return tx.Unscoped().Where("id IN ?", ids).Delete(&models.User{}).Error
+		}
+
+		for _, id := range ids {
+			err := tx.Unscoped().Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+				"user_name":                "deleted",
+				"email":                    fmt.Sprintf("deleted-%d@deleted.invalid", id),
+				"password":                 "",
+				"phone_number":             "",
+				"avatar_url":               "",
+				"block_reason":             "",
+				"pending_email":            "",
+				"pending_email_token_hash": "",
+				"mfa_enabled":              false,
+				"mfa_secret":               "",
+				"mfa_pending_secret":       "",
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, models.Internal("failed to purge deleted users", err)
+	}
+	return users, nil
+}
@@ -0,0 +1,72 @@
+package repository_test
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserStorage_ReplicaOnlyServesListings(t *testing.T) {
+	primary, primaryMock := mockDB(t)
+	replica, replicaMock := mockDB(t)
+	repo := repository.NewUserRepository(primary)
+	repo.Replica = replica
+
+	primaryMock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "john@example.com"))
+	replicaMock.ExpectQuery(`SELECT count\(\*\) FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	replicaMock.ExpectQuery(`SELECT \* FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "john@example.com"))
+
+	user, err := repo.FindUserByID(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "john@example.com", user.Email)
+
+	users, total, err := repo.ListUsers(context.Background(), models.UserFilter{Page: 1, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Len(t, users, 1)
+
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestListUsers_FiltersByAssignedRole(t *testing.T) {
+	db, mock := mockDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "users" WHERE (EXISTS (SELECT 1 FROM user_roles JOIN roles ON roles.id = user_roles.role_id WHERE user_roles.user_id = users.id AND roles.name = $1))`)).
+		WithArgs("support").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(`roles.name = $1`)).
+		WithArgs("support", 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(3, "support@example.com"))
+
+	users, total, err := repository.NewUserRepository(db).ListUsers(context.Background(), models.UserFilter{Role: "support", Page: 1, Limit: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, 3, users[0].ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateUser_WritesOnlyNamedColumns(t *testing.T) {
+	db, mock := mockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "status"=$1,"block_reason"=$2,"updated_at"=$3 WHERE "id" = $4`)).
+		WithArgs(models.StatusBlocked, "spam", sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	user := &models.User{ID: 2, UserName: "stale", Password: "stale-hash", Status: models.StatusBlocked, BlockReason: "spam"}
+	assert.NoError(t, repository.NewUserRepository(db).UpdateUser(context.Background(), user, "status", "block_reason"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
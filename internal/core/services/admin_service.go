@@ -0,0 +1,148 @@
+package services
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"clean-arch/internal/validation"
+	"context"
+)
+
+type AdminService interface {
+	ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int64, error)
+	GetUser(ctx context.Context, userID int) (*models.User, error)
+	BlockUser(ctx context.Context, actorID, userID int, reason string) error
+	UnblockUser(ctx context.Context, actorID, userID int) error
+	UnlockUser(ctx context.Context, userID int) error
+	ForceLogout(ctx context.Context, userID int) error
+	BootstrapAdmin(ctx context.Context, email, password string) (bool, error)
+}
+
+type AdminServiceImpl struct {
+	userRepo     repository.UserRespository
+	roleRepo     repository.RoleRepository
+	tokenService TokenService
+	loginGuard   LoginGuard
+}
+
+func NewAdminService(userRepo repository.UserRespository, roleRepo repository.RoleRepository, tokenService TokenService, loginGuard LoginGuard) *AdminServiceImpl {
+	return &AdminServiceImpl{
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		tokenService: tokenService,
+		loginGuard:   loginGuard,
+	}
+}
+
+func (s *AdminServiceImpl) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int64, error) {
+	filter.Normalize()
+	return s.userRepo.ListUsers(ctx, filter)
+}
+
+func (s *AdminServiceImpl) GetUser(ctx context.Context, userID int) (*models.User, error) {
+	user, err := s.userRepo.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, models.ErrUserDoesNotExist
+	}
+	return user, nil
+}
+
+// BlockUser blocks the account and ends all of its sessions, so the block takes
+// effect immediately rather than when the current access token expires.
+func (s *AdminServiceImpl) BlockUser(ctx context.Context, actorID, userID int, reason string) error {
+	if actorID == userID {
+		return models.ErrCannotModifySelf
+	}
+
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	user.Status = models.StatusBlocked
+	user.BlockReason = reason
+	if err := s.userRepo.UpdateUser(ctx, user, "status", "block_reason"); err != nil {
+		return err
+	}
+
+	return s.tokenService.LogoutAll(ctx, user.ID)
+}
+
+func (s *AdminServiceImpl) UnblockUser(ctx context.Context, actorID, userID int) error {
+	if actorID == userID {
+		return models.ErrCannotModifySelf
+	}
+
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	user.Status = models.StatusActive
+	user.BlockReason = ""
+	return s.userRepo.UpdateUser(ctx, user, "status", "block_reason")
+}
+
+// UnlockUser lifts a lockout caused by failed login attempts before it would
+// expire on its own.
+func (s *AdminServiceImpl) UnlockUser(ctx context.Context, userID int) error {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.loginGuard.Unlock(user.Email)
+}
+
+func (s *AdminServiceImpl) ForceLogout(ctx context.Context, userID int) error {
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.tokenService.LogoutAll(ctx, userID)
+}
+
+// BootstrapAdmin makes sure at least one account is assigned the admin role.
+// If none is, the account with the given email is promoted, or created when
+// missing. It reports whether anything was changed. The admin role must have
+// been seeded.
+func (s *AdminServiceImpl) BootstrapAdmin(ctx context.Context, email, password string) (bool, error) {
+	admins, err := s.roleRepo.CountRoleUsers(models.RoleAdmin)
+	if err != nil {
+		return false, err
+	}
+	if admins > 0 {
+		return false, nil
+	}
+
+	role, err := s.roleRepo.FindRoleByName(models.RoleAdmin)
+	if err != nil {
+		return false, err
+	}
+
+	if user, err := s.userRepo.FindUserByEmail(ctx, email); err == nil {
+		return true, s.roleRepo.AssignUserRole(user.ID, role)
+	}
+
+	credentials := struct {
+		Email    string `json:"admin_email" validate:"required,email"`
+		Password string `json:"admin_password" validate:"required,password"`
+	}{email, password}
+	if err := validation.Struct(validation.WithIdentities(ctx, email), credentials); err != nil {
+		return false, err
+	}
+
+	hashedPassword, err := hashPassword(ctx, password)
+	if err != nil {
+		return false, err
+	}
+
+	admin := &models.User{
+		UserName: "admin",
+		Email:    email,
+		Password: string(hashedPassword),
+		Status:   models.StatusActive,
+		Role:     models.RoleAdmin,
+	}
+	if err := s.userRepo.CreateUser(ctx, admin); err != nil {
+		return false, err
+	}
+	return true, s.roleRepo.AssignUserRole(admin.ID, role)
+}
@@ -0,0 +1,122 @@
+package services_test
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"clean-arch/internal/core/services"
+	"clean-arch/internal/mocks"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newAdminService(userRepo *mocks.MockUserRepository, tokenRepo *mocks.MockRefreshTokenRepository, roleRepo *mocks.MockRoleRepository) *services.AdminServiceImpl {
+	tokenService := services.NewTokenService(tokenRepo, repository.NewInMemoryRevocationStore(), userRepo, time.Minute, time.Hour)
+	loginGuard := services.NewLoginGuard(repository.NewInMemoryLoginAttemptStore(), services.LoginGuardConfig{})
+	return services.NewAdminService(userRepo, roleRepo, tokenService, loginGuard)
+}
+
+func TestListUsers_NormalizesPaging(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	adminService := newAdminService(mockUserRepo, new(mocks.MockRefreshTokenRepository), new(mocks.MockRoleRepository))
+
+	expected := models.UserFilter{Status: models.StatusBlocked, Page: 1, Limit: models.MaxPageSize, SortDesc: true}
+	mockUserRepo.On("ListUsers", expected).Return([]models.User{{ID: 2}}, int64(1), nil)
+
+	users, total, err := adminService.ListUsers(context.Background(), models.UserFilter{Status: models.StatusBlocked, Page: 0, Limit: 1000, SortDesc: true})
+
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, int64(1), total)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestBlockUser_EndsSessions(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockTokenRepo := new(mocks.MockRefreshTokenRepository)
+	adminService := newAdminService(mockUserRepo, mockTokenRepo, new(mocks.MockRoleRepository))
+
+	mockUserRepo.On("FindUserByID", 2).Return(&models.User{ID: 2, Status: models.StatusActive}, nil)
+	mockUserRepo.On("UpdateUser", mock.MatchedBy(func(user *models.User) bool {
+		return user.Status == models.StatusBlocked && user.BlockReason == "spam"
+	}), []string{"status", "block_reason"}).Return(nil)
+	mockTokenRepo.On("RevokeUserRefreshTokens", 2, mock.AnythingOfType("time.Time")).Return(nil)
+
+	err := adminService.BlockUser(context.Background(), 1, 2, "spam")
+
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestBlockUser_CannotBlockSelf(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	adminService := newAdminService(mockUserRepo, new(mocks.MockRefreshTokenRepository), new(mocks.MockRoleRepository))
+
+	err := adminService.BlockUser(context.Background(), 1, 1, "oops")
+
+	assert.ErrorIs(t, err, models.ErrCannotModifySelf)
+	mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestBootstrapAdmin_CreatesFirstAdmin(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockRoleRepo := new(mocks.MockRoleRepository)
+	adminService := newAdminService(mockUserRepo, new(mocks.MockRefreshTokenRepository), mockRoleRepo)
+
+	adminRole := &models.Role{ID: 2, Name: models.RoleAdmin}
+	mockRoleRepo.On("CountRoleUsers", models.RoleAdmin).Return(int64(0), nil)
+	mockRoleRepo.On("FindRoleByName", models.RoleAdmin).Return(adminRole, nil)
+	mockUserRepo.On("FindUserByEmail", "admin@example.com").Return(nil, errors.New("user not found"))
+	mockUserRepo.On("CreateUser", mock.MatchedBy(func(user *models.User) bool {
+		return user.Role == models.RoleAdmin && user.Email == "admin@example.com" && user.Password != "adminpass1"
+	})).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.User).ID = 7
+	}).Return(nil)
+	mockRoleRepo.On("AssignUserRole", 7, adminRole).Return(nil)
+
+	created, err := adminService.BootstrapAdmin(context.Background(), "admin@example.com", "adminpass1")
+
+	assert.NoError(t, err)
+	assert.True(t, created)
+	mockUserRepo.AssertExpectations(t)
+	mockRoleRepo.AssertExpectations(t)
+}
+
+func TestBootstrapAdmin_PromotesExistingAccount(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockRoleRepo := new(mocks.MockRoleRepository)
+	adminService := newAdminService(mockUserRepo, new(mocks.MockRefreshTokenRepository), mockRoleRepo)
+
+	adminRole := &models.Role{ID: 2, Name: models.RoleAdmin}
+	mockRoleRepo.On("CountRoleUsers", models.RoleAdmin).Return(int64(0), nil)
+	mockRoleRepo.On("FindRoleByName", models.RoleAdmin).Return(adminRole, nil)
+	mockUserRepo.On("FindUserByEmail", "admin@example.com").Return(&models.User{ID: 3, Email: "admin@example.com", Role: models.RoleUser}, nil)
+	mockRoleRepo.On("AssignUserRole", 3, adminRole).Return(nil)
+
+	created, err := adminService.BootstrapAdmin(context.Background(), "admin@example.com", "adminpass1")
+
+	assert.NoError(t, err)
+	assert.True(t, created)
+	mockRoleRepo.AssertExpectations(t)
+	mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestBootstrapAdmin_SkipsWhenAdminRoleIsAssigned(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockRoleRepo := new(mocks.MockRoleRepository)
+	adminService := newAdminService(mockUserRepo, new(mocks.MockRefreshTokenRepository), mockRoleRepo)
+
+	mockRoleRepo.On("CountRoleUsers", models.RoleAdmin).Return(int64(1), nil)
+
+	created, err := adminService.BootstrapAdmin(context.Background(), "admin@example.com", "adminpass1")
+
+	assert.NoError(t, err)
+	assert.False(t, created)
+	mockUserRepo.AssertNotCalled(t, "ListUsers", mock.Anything)
+	mockUserRepo.AssertNotCalled(t, "CreateUser", mock.Anything)
+}
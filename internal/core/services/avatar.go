@@ -0,0 +1,131 @@
+package services
+
+import (
+	"bytes"
+	"clean-arch/internal/core/models"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var allowedAvatarTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// readAvatar reads an uploaded picture, enforcing the size limit and checking
+// the actual content rather than the client-provided content type.
+func readAvatar(file io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(io.LimitReader(file, models.MaxProfilePictureSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > models.MaxProfilePictureSize {
+		return nil, models.ErrFileTooLarge
+	}
+
+	if !allowedAvatarTypes[http.DetectContentType(data)] {
+		return nil, models.ErrUnsupportedImage
+	}
+
+	// Check dimensions before decoding to avoid decompression bombs.
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, models.ErrUnsupportedImage
+	}
+	if config.Width > models.MaxProfilePictureDimension || config.Height > models.MaxProfilePictureDimension {
+		return nil, models.ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, models.ErrUnsupportedImage
+	}
+	return img, nil
+}
+
+// thumbnail center-crops img to a square and scales it down to size x size
+// by averaging the source pixels that fall into each target pixel.
+func thumbnail(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	crop := image.Rect(0, 0, side, side)
+	offset := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+
+	src := image.NewRGBA(crop)
+	draw.Draw(src, crop, img, offset, draw.Src)
+
+	if size > side {
+		size = side
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	for y := 0; y < size; y++ {
+		y0, y1 := y*side/size, (y+1)*side/size
+		for x := 0; x < size; x++ {
+			x0, x1 := x*side/size, (x+1)*side/size
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
+
+func encodePNG(img image.Image) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+func avatarKey(userID int, version string, size int) string {
+	return fmt.Sprintf("avatars/%d/%s-%d.png", userID, version, size)
+}
+
+// avatarKeys returns the keys of the avatar and thumbnail stored together
+// with avatarURL, or nil when the URL was not produced by an upload.
+func avatarKeys(userID int, avatarURL string) []string {
+	prefix := fmt.Sprintf("avatars/%d/", userID)
+	i := strings.LastIndex(avatarURL, prefix)
+	if i < 0 {
+		return nil
+	}
+
+	version, ok := strings.CutSuffix(avatarURL[i+len(prefix):], fmt.Sprintf("-%d.png", models.AvatarSize))
+	if !ok || version == "" || strings.Contains(version, "/") {
+		return nil
+	}
+	return []string{
+		avatarKey(userID, version, models.AvatarSize),
+		avatarKey(userID, version, models.AvatarThumbnailSize),
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"strings"
+	"time"
+)
+
+// LoginGuardConfig tunes login throttling. Zero values fall back to the
+// defaults in models.
+type LoginGuardConfig struct {
+	MaxFailures   int
+	IPMaxFailures int
+	LockoutWindow time.Duration
+}
+
+// LoginGuard throttles password and MFA attempts per account and per client
+// IP. It is consulted before the password is checked, so a locked account
+// costs no bcrypt comparison.
+type LoginGuard interface {
+	Check(email, ip string) error
+	RecordFailure(email, ip string) error
+	RecordSuccess(email string) error
+	Unlock(email string) error
+	PurgeExpired() error
+}
+
+type LoginGuardImpl struct {
+	store  repository.LoginAttemptStore
+	config LoginGuardConfig
+}
+
+func NewLoginGuard(store repository.LoginAttemptStore, config LoginGuardConfig) *LoginGuardImpl {
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = models.DefaultLoginMaxFailures
+	}
+	if config.IPMaxFailures <= 0 {
+		config.IPMaxFailures = models.DefaultLoginIPMaxFailures
+	}
+	if config.LockoutWindow <= 0 {
+		config.LockoutWindow = models.DefaultLoginLockoutWindow
+	}
+	return &LoginGuardImpl{
+		store:  store,
+		config: config,
+	}
+}
+
+// Check returns a *models.LoginThrottledError wrapping ErrAccountLocked when
+// the account has reached its failure threshold, or ErrTooManyLoginAttempts
+// while a backoff delay or an IP lockout is running.
+func (g *LoginGuardImpl) Check(email, ip string) error {
+	now := time.Now()
+
+	if err := g.check(accountKey(email), g.config.MaxFailures, models.ErrAccountLocked, now); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return g.check(ipKey(ip), g.config.IPMaxFailures, models.ErrTooManyLoginAttempts, now)
+}
+
+func (g *LoginGuardImpl) check(key string, maxFailures int, lockedErr error, now time.Time) error {
+	attempt, err := g.store.FindLoginAttempt(key)
+	if err != nil || attempt == nil {
+		return err
+	}
+
+	// Counters reset, and lockouts lift, once the window has passed since
+	// the last failure.
+	if now.Sub(attempt.LastFailureAt) >= g.config.LockoutWindow {
+		return nil
+	}
+
+	if attempt.Failures >= maxFailures {
+		return &models.LoginThrottledError{
+			Err:        lockedErr,
+			RetryAfter: attempt.LastFailureAt.Add(g.config.LockoutWindow).Sub(now),
+		}
+	}
+
+	if until := attempt.LastFailureAt.Add(backoff(attempt.Failures)); now.Before(until) {
+		return &models.LoginThrottledError{
+			Err:        models.ErrTooManyLoginAttempts,
+			RetryAfter: until.Sub(now),
+		}
+	}
+	return nil
+}
+
+func (g *LoginGuardImpl) RecordFailure(email, ip string) error {
+	now := time.Now()
+	if _, err := g.store.RecordLoginFailure(accountKey(email), now, g.config.LockoutWindow); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	_, err := g.store.RecordLoginFailure(ipKey(ip), now, g.config.LockoutWindow)
+	return err
+}
+
+// RecordSuccess clears the account counter. The IP counter is left alone so
+// an attacker cannot reset it by logging into an account of their own.
+func (g *LoginGuardImpl) RecordSuccess(email string) error {
+	return g.store.ResetLoginAttempts(accountKey(email))
+}
+
+func (g *LoginGuardImpl) Unlock(email string) error {
+	return g.store.ResetLoginAttempts(accountKey(email))
+}
+
+func (g *LoginGuardImpl) PurgeExpired() error {
+	return g.store.DeleteLoginAttemptsBefore(time.Now().Add(-g.config.LockoutWindow))
+}
+
+// backoff is the wait after the given number of consecutive failures.
+func backoff(failures int) time.Duration {
+	delay := models.LoginBackoffBase
+	for i := 1; i < failures && delay < models.LoginBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > models.LoginBackoffMax {
+		delay = models.LoginBackoffMax
+	}
+	return delay
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,53 @@
+package services_test
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"clean-arch/internal/core/services"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginGuard_LockoutExpiresAfterWindow(t *testing.T) {
+	store := repository.NewInMemoryLoginAttemptStore()
+	guard := services.NewLoginGuard(store, services.LoginGuardConfig{MaxFailures: 2, LockoutWindow: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		_, err := store.RecordLoginFailure("account:johndoe@gmail.com", time.Now().Add(-30*time.Second), time.Minute)
+		assert.NoError(t, err)
+	}
+
+	err := guard.Check("JohnDoe@gmail.com", "")
+	assert.ErrorIs(t, err, models.ErrAccountLocked)
+
+	var throttled *models.LoginThrottledError
+	assert.True(t, errors.As(err, &throttled))
+	assert.InDelta(t, 30*time.Second, throttled.RetryAfter, float64(time.Second))
+
+	_, err = store.RecordLoginFailure("account:other@gmail.com", time.Now().Add(-2*time.Minute), time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, guard.Check("other@gmail.com", ""))
+}
+
+func TestLoginGuard_IPThreshold(t *testing.T) {
+	guard := services.NewLoginGuard(repository.NewInMemoryLoginAttemptStore(), services.LoginGuardConfig{IPMaxFailures: 1})
+
+	assert.NoError(t, guard.RecordFailure("a@gmail.com", "10.0.0.1"))
+
+	assert.ErrorIs(t, guard.Check("b@gmail.com", "10.0.0.1"), models.ErrTooManyLoginAttempts)
+	assert.NoError(t, guard.Check("b@gmail.com", "10.0.0.2"))
+}
+
+func TestLoginGuard_Unlock(t *testing.T) {
+	store := repository.NewInMemoryLoginAttemptStore()
+	guard := services.NewLoginGuard(store, services.LoginGuardConfig{MaxFailures: 1})
+
+	assert.NoError(t, guard.RecordFailure("johndoe@gmail.com", ""))
+	assert.ErrorIs(t, guard.Check("johndoe@gmail.com", ""), models.ErrAccountLocked)
+
+	assert.NoError(t, guard.Unlock("johndoe@gmail.com"))
+	assert.NoError(t, guard.Check("johndoe@gmail.com", ""))
+}
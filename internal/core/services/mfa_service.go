@@ -0,0 +1,241 @@
+package services
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"clean-arch/internal/logger"
+	"clean-arch/internal/secrets"
+	"clean-arch/internal/totp"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// mfaColumns are written when two-factor authentication is switched on or off.
+var mfaColumns = []string{"mfa_enabled", "mfa_secret", "mfa_pending_secret", "mfa_last_used_step"}
+
+type MFAService interface {
+	BeginEnrollment(ctx context.Context, userID int) (*models.MFAEnrollment, error)
+	ConfirmEnrollment(ctx context.Context, userID int, code string) ([]string, error)
+	Disable(ctx context.Context, userID int, input *models.MFADisableInput) error
+	Verify(ctx context.Context, userID int, code string) (*models.User, error)
+}
+
+type MFAServiceImpl struct {
+	userRepo     repository.UserRespository
+	recoveryRepo repository.RecoveryCodeRepository
+	box          *secrets.Box
+}
+
+func NewMFAService(userRepo repository.UserRespository, recoveryRepo repository.RecoveryCodeRepository, box *secrets.Box) *MFAServiceImpl {
+	return &MFAServiceImpl{
+		userRepo:     userRepo,
+		recoveryRepo: recoveryRepo,
+		box:          box,
+	}
+}
+
+// BeginEnrollment generates a new secret and keeps it pending until the user
+// proves their authenticator works by confirming a first code.
+func (s *MFAServiceImpl) BeginEnrollment(ctx context.Context, userID int) (*models.MFAEnrollment, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, models.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.box.Seal(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	user.MFAPendingSecret = sealed
+	if err := s.userRepo.UpdateUser(ctx, user, "mfa_pending_secret"); err != nil {
+		return nil, err
+	}
+
+	return &models.MFAEnrollment{
+		Secret: secret,
+		URI:    totp.URI(models.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables two-factor authentication and returns the
+// recovery codes. They are only ever shown here.
+func (s *MFAServiceImpl) ConfirmEnrollment(ctx context.Context, userID int, code string) ([]string, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, models.ErrMFAAlreadyEnabled
+	}
+	if user.MFAPendingSecret == "" {
+		return nil, models.ErrMFANotEnrolling
+	}
+
+	ok, err := s.checkTOTP(user, user.MFAPendingSecret, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.ErrInvalidMFACode
+	}
+
+	codes, stored, err := newRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recoveryRepo.ReplaceRecoveryCodes(user.ID, stored); err != nil {
+		return nil, err
+	}
+
+	user.MFASecret = user.MFAPendingSecret
+	user.MFAPendingSecret = ""
+	user.MFAEnabled = true
+	if err := s.userRepo.UpdateUser(ctx, user, mfaColumns...); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns two-factor authentication off. It requires both the password
+// and a current code or recovery code.
+func (s *MFAServiceImpl) Disable(ctx context.Context, userID int, input *models.MFADisableInput) error {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return models.ErrMFANotEnabled
+	}
+
+	if err := comparePassword(ctx, user.Password, input.Password); err != nil {
+		return models.ErrIncorrectPassword
+	}
+
+	if err := s.verifyCode(ctx, user, input.Code); err != nil {
+		return err
+	}
+
+	if err := s.recoveryRepo.DeleteRecoveryCodes(user.ID); err != nil {
+		return err
+	}
+
+	user.MFAEnabled = false
+	user.MFASecret = ""
+	user.MFAPendingSecret = ""
+	user.MFALastUsedStep = 0
+	return s.userRepo.UpdateUser(ctx, user, mfaColumns...)
+}
+
+// Verify completes the second login step with either a TOTP code or an
+// unused recovery code. A deleted account gets here only when Login found it
+// within the grace period, and it is restored once the code is accepted.
+func (s *MFAServiceImpl) Verify(ctx context.Context, userID int, code string) (*models.User, error) {
+	user, err := s.userRepo.FindUserByID(ctx, userID)
+	if err != nil {
+		user, err = s.userRepo.FindDeletedUserByID(ctx, userID)
+		if err != nil {
+			return nil, models.ErrUserDoesNotExist
+		}
+	}
+	if !user.MFAEnabled {
+		return nil, models.ErrMFANotEnabled
+	}
+
+	if err := s.verifyCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	if user.DeletedAt.Valid {
+		if err := s.userRepo.RestoreUser(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		user.DeletedAt = gorm.DeletedAt{}
+		logger.FromContext(ctx).WithFields(logger.Fields{"user_id": user.ID}).Info("Restored deleted account on login")
+	}
+	return user, nil
+}
+
+func (s *MFAServiceImpl) verifyCode(ctx context.Context, user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+
+	ok, err := s.checkTOTP(user, user.MFASecret, code)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return s.userRepo.UpdateUser(ctx, user, "mfa_last_used_step")
+	}
+
+	used, err := s.recoveryRepo.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return models.ErrInvalidMFACode
+	}
+	return nil
+}
+
+// checkTOTP validates code against the sealed secret. A code is accepted at
+// most once: its time step is recorded on the user, and the caller saves it.
+func (s *MFAServiceImpl) checkTOTP(user *models.User, sealed, code string) (bool, error) {
+	secret, err := s.box.Open(sealed)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok || step <= user.MFALastUsedStep {
+		return false, nil
+	}
+	user.MFALastUsedStep = step
+	return true, nil
+}
+
+func (s *MFAServiceImpl) findUser(ctx context.Context, userID int) (*models.User, error) {
+	user, err := s.userRepo.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, models.ErrUserDoesNotExist
+	}
+	return user, nil
+}
+
+// newRecoveryCodes returns the codes to show to the user, formatted as
+// xxxxx-xxxxx, and the hashed records to store.
+func newRecoveryCodes(userID int) ([]string, []models.RecoveryCode, error) {
+	codes := make([]string, 0, models.RecoveryCodeCount)
+	stored := make([]models.RecoveryCode, 0, models.RecoveryCodeCount)
+	for i := 0; i < models.RecoveryCodeCount; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))[:10]
+
+		codes = append(codes, code[:5]+"-"+code[5:])
+		stored = append(stored, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashToken(code),
+		})
+	}
+	return codes, stored, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
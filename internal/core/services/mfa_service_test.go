@@ -0,0 +1,126 @@
+package services_test
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/services"
+	"clean-arch/internal/mocks"
+	"clean-arch/internal/secrets"
+	"clean-arch/internal/totp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func newMFAService(t *testing.T, userRepo *mocks.MockUserRepository, recoveryRepo *mocks.MockRecoveryCodeRepository) (*services.MFAServiceImpl, *secrets.Box) {
+	box, err := secrets.NewBox([]byte("test-key"))
+	assert.NoError(t, err)
+	return services.NewMFAService(userRepo, recoveryRepo, box), box
+}
+
+func TestConfirmEnrollment_EnablesMFA(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockRecoveryRepo := new(mocks.MockRecoveryCodeRepository)
+	mfaService, box := newMFAService(t, mockUserRepo, mockRecoveryRepo)
+
+	secret, _ := totp.GenerateSecret()
+	sealed, _ := box.Seal(secret)
+	code, _ := totp.Code(secret, totp.Step(time.Now()))
+
+	user := &models.User{ID: 1, Email: "johndoe@gmail.com", MFAPendingSecret: sealed}
+	mockUserRepo.On("FindUserByID", 1).Return(user, nil)
+	mockRecoveryRepo.On("ReplaceRecoveryCodes", 1, mock.MatchedBy(func(codes []models.RecoveryCode) bool {
+		return len(codes) == models.RecoveryCodeCount
+	})).Return(nil)
+	mockUserRepo.On("UpdateUser", mock.MatchedBy(func(user *models.User) bool {
+		return user.MFAEnabled && user.MFASecret == sealed && user.MFAPendingSecret == ""
+	}), []string{"mfa_enabled", "mfa_secret", "mfa_pending_secret", "mfa_last_used_step"}).Return(nil)
+
+	codes, err := mfaService.ConfirmEnrollment(context.Background(), 1, code)
+
+	assert.NoError(t, err)
+	assert.Len(t, codes, models.RecoveryCodeCount)
+	mockUserRepo.AssertExpectations(t)
+	mockRecoveryRepo.AssertExpectations(t)
+}
+
+func TestVerify_RejectsReusedCode(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockRecoveryRepo := new(mocks.MockRecoveryCodeRepository)
+	mfaService, box := newMFAService(t, mockUserRepo, mockRecoveryRepo)
+
+	secret, _ := totp.GenerateSecret()
+	sealed, _ := box.Seal(secret)
+	step := totp.Step(time.Now())
+	code, _ := totp.Code(secret, step)
+
+	user := &models.User{ID: 1, MFAEnabled: true, MFASecret: sealed, MFALastUsedStep: step}
+	mockUserRepo.On("FindUserByID", 1).Return(user, nil)
+	mockRecoveryRepo.On("UseRecoveryCode", 1, mock.Anything, mock.AnythingOfType("time.Time")).Return(false, nil)
+
+	verified, err := mfaService.Verify(context.Background(), 1, code)
+
+	assert.Nil(t, verified)
+	assert.ErrorIs(t, err, models.ErrInvalidMFACode)
+	mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestVerify_AcceptsRecoveryCode(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockRecoveryRepo := new(mocks.MockRecoveryCodeRepository)
+	mfaService, box := newMFAService(t, mockUserRepo, mockRecoveryRepo)
+
+	secret, _ := totp.GenerateSecret()
+	sealed, _ := box.Seal(secret)
+
+	sum := sha256.Sum256([]byte("abcde12345"))
+	mockUserRepo.On("FindUserByID", 1).Return(&models.User{ID: 1, MFAEnabled: true, MFASecret: sealed}, nil)
+	mockRecoveryRepo.On("UseRecoveryCode", 1, hex.EncodeToString(sum[:]), mock.AnythingOfType("time.Time")).Return(true, nil)
+
+	verified, err := mfaService.Verify(context.Background(), 1, "ABCDE-12345")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, verified.ID)
+	mockRecoveryRepo.AssertExpectations(t)
+}
+
+func TestVerify_RestoresDeletedAccountOnlyAfterCode(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockRecoveryRepo := new(mocks.MockRecoveryCodeRepository)
+	mfaService, box := newMFAService(t, mockUserRepo, mockRecoveryRepo)
+
+	secret, _ := totp.GenerateSecret()
+	sealed, _ := box.Seal(secret)
+	code, _ := totp.Code(secret, totp.Step(time.Now()))
+
+	deleted := func() *models.User {
+		return &models.User{
+			ID:         1,
+			MFAEnabled: true,
+			MFASecret:  sealed,
+			DeletedAt:  gorm.DeletedAt{Time: time.Now().Add(-time.Hour), Valid: true},
+		}
+	}
+	mockUserRepo.On("FindUserByID", 1).Return(nil, models.ErrUserDoesNotExist)
+	mockUserRepo.On("FindDeletedUserByID", 1).Return(deleted(), nil).Once()
+	mockRecoveryRepo.On("UseRecoveryCode", 1, mock.Anything, mock.AnythingOfType("time.Time")).Return(false, nil)
+
+	_, err := mfaService.Verify(context.Background(), 1, "00000000")
+	assert.ErrorIs(t, err, models.ErrInvalidMFACode)
+	mockUserRepo.AssertNotCalled(t, "RestoreUser", mock.Anything)
+
+	mockUserRepo.On("FindDeletedUserByID", 1).Return(deleted(), nil).Once()
+	mockUserRepo.On("UpdateUser", mock.AnythingOfType("*models.User"), mock.Anything).Return(nil)
+	mockUserRepo.On("RestoreUser", 1).Return(nil)
+
+	verified, err := mfaService.Verify(context.Background(), 1, code)
+
+	assert.NoError(t, err)
+	assert.False(t, verified.DeletedAt.Valid)
+	mockUserRepo.AssertExpectations(t)
+}
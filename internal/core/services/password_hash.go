@@ -0,0 +1,45 @@
+package services
+
+import (
+	"clean-arch/internal/metrics"
+	"clean-arch/internal/tracing"
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptCost is the work factor for new password hashes. Existing hashes
+// keep the cost they were created with.
+var BcryptCost = bcrypt.DefaultCost
+
+func hashPassword(ctx context.Context, password string) ([]byte, error) {
+	_, span := tracing.Start(ctx, "bcrypt.hash")
+	defer span.End()
+	defer metrics.ObserveBcrypt("hash", time.Now())
+	return bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash is compared against when there is no account to check a
+// password against, so that failing for an unknown email takes as long as
+// failing for a known one. It uses the current BcryptCost.
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), BcryptCost)
+		dummyHash = string(hash)
+	})
+	return dummyHash
+}
+
+func comparePassword(ctx context.Context, hash, password string) error {
+	_, span := tracing.Start(ctx, "bcrypt.compare")
+	defer span.End()
+	defer metrics.ObserveBcrypt("compare", time.Now())
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"clean-arch/internal/logger"
+	"clean-arch/internal/mailer"
+	"clean-arch/internal/validation"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type PasswordService interface {
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, input *models.ResetPasswordInput) error
+	ChangePassword(ctx context.Context, userID int, input *models.PasswordReset) error
+}
+
+type PasswordServiceImpl struct {
+	userRepo     repository.UserRespository
+	resetRepo    repository.PasswordResetRepository
+	historyRepo  repository.PasswordHistoryRepository
+	tokenService TokenService
+	mailer       mailer.Mailer
+	resetLinkURL string
+	sending      sync.WaitGroup
+}
+
+func NewPasswordService(userRepo repository.UserRespository, resetRepo repository.PasswordResetRepository, historyRepo repository.PasswordHistoryRepository, tokenService TokenService, mailer mailer.Mailer, resetLinkURL string) *PasswordServiceImpl {
+	return &PasswordServiceImpl{
+		userRepo:     userRepo,
+		resetRepo:    resetRepo,
+		historyRepo:  historyRepo,
+		tokenService: tokenService,
+		mailer:       mailer,
+		resetLinkURL: resetLinkURL,
+	}
+}
+
+// ForgotPassword mails a single-use reset token in the background and always
+// succeeds, so neither the response nor its timing reveals which accounts
+// exist. Failures are only logged.
+func (s *PasswordServiceImpl) ForgotPassword(ctx context.Context, email string) error {
+	ctx = context.WithoutCancel(ctx)
+	s.sending.Add(1)
+	go func() {
+		defer s.sending.Done()
+		if err := s.sendReset(ctx, email); err != nil {
+			logger.FromContext(ctx).Error("Failed to send password reset email", err.Error())
+		}
+	}()
+	return nil
+}
+
+// Wait blocks until the reset emails started by ForgotPassword are sent, or
+// ctx is done.
+func (s *PasswordServiceImpl) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.sending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendReset replaces any outstanding reset token of the account with email
+// and mails the new one. Unknown emails are ignored.
+func (s *PasswordServiceImpl) sendReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.FindUserByEmail(ctx, email)
+	if errors.Is(err, models.ErrUserDoesNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.resetRepo.InvalidateUserPasswordResets(user.ID, now); err != nil {
+		return err
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+
+	reset := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(models.PasswordResetTokenTTL),
+	}
+	if err := s.resetRepo.CreatePasswordReset(reset); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nUse this code to reset your password:\n\n%s\n\n", user.UserName, token)
+	if s.resetLinkURL != "" {
+		body += fmt.Sprintf("You can also open this link:\n\n%s?token=%s\n\n", s.resetLinkURL, url.QueryEscape(token))
+	}
+	body += fmt.Sprintf("The code expires at %s. If you did not request a reset, ignore this email.\n", reset.ExpiresAt.Format(time.RFC1123))
+
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    body,
+	})
+}
+
+// ResetPassword consumes a reset token, stores the new password and ends all
+// existing sessions of the user.
+func (s *PasswordServiceImpl) ResetPassword(ctx context.Context, input *models.ResetPasswordInput) error {
+	now := time.Now()
+	reset, user, tokenErr := s.findReset(ctx, input.Token, now)
+
+	// Invalid input is reported first, and against the account when the
+	// token identifies one, so that every policy violation comes at once.
+	validationCtx := ctx
+	if tokenErr == nil {
+		validationCtx = validation.WithIdentities(ctx, user.UserName, user.Email)
+	}
+	if err := validation.Struct(validationCtx, input); err != nil {
+		return err
+	}
+	if tokenErr != nil {
+		return tokenErr
+	}
+
+	// Check reuse before consuming the token so the user can pick another
+	// password with the same link.
+	if err := s.checkPasswordReuse(ctx, user, input.NewPassword); err != nil {
+		return err
+	}
+
+	marked, err := s.resetRepo.MarkPasswordResetUsed(reset.ID, now)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return models.ErrInvalidResetToken
+	}
+
+	if err := s.storePassword(ctx, user, input.NewPassword); err != nil {
+		return err
+	}
+
+	return s.tokenService.LogoutAll(ctx, user.ID)
+}
+
+// findReset looks up an unused, unexpired reset token and its user.
+func (s *PasswordServiceImpl) findReset(ctx context.Context, token string, now time.Time) (*models.PasswordResetToken, *models.User, error) {
+	if token == "" {
+		return nil, nil, models.ErrInvalidResetToken
+	}
+
+	reset, err := s.resetRepo.FindPasswordResetByHash(hashToken(token))
+	if err != nil {
+		return nil, nil, models.ErrInvalidResetToken
+	}
+	if reset.UsedAt != nil || now.After(reset.ExpiresAt) {
+		return nil, nil, models.ErrInvalidResetToken
+	}
+
+	user, err := s.userRepo.FindUserByID(ctx, reset.UserID)
+	if err != nil {
+		return nil, nil, models.ErrInvalidResetToken
+	}
+	return reset, user, nil
+}
+
+// ChangePassword replaces the password of a logged-in user after checking the
+// current one.
+func (s *PasswordServiceImpl) ChangePassword(ctx context.Context, userID int, input *models.PasswordReset) error {
+	user, err := s.userRepo.FindUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := validation.Struct(validation.WithIdentities(ctx, user.UserName, user.Email), input); err != nil {
+		return err
+	}
+
+	if err := comparePassword(ctx, user.Password, input.CurrentPassword); err != nil {
+		return models.ErrIncorrectPassword
+	}
+
+	if err := s.checkPasswordReuse(ctx, user, input.NewPassword); err != nil {
+		return err
+	}
+
+	return s.storePassword(ctx, user, input.NewPassword)
+}
+
+// checkPasswordReuse rejects the current password and the last
+// PasswordHistoryLimit ones.
+func (s *PasswordServiceImpl) checkPasswordReuse(ctx context.Context, user *models.User, password string) error {
+	recent, err := s.historyRepo.FindRecentPasswordHashes(user.ID, models.PasswordHistoryLimit)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range append(recent, user.Password) {
+		if comparePassword(ctx, hash, password) == nil {
+			return models.ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// storePassword saves the new hash and records it in the password history.
+func (s *PasswordServiceImpl) storePassword(ctx context.Context, user *models.User, password string) error {
+	hashedPassword, err := hashPassword(ctx, password)
+	if err != nil {
+		return err
+	}
+
+	user.Password = string(hashedPassword)
+	if err := s.userRepo.UpdateUser(ctx, user, "password"); err != nil {
+		return err
+	}
+
+	return s.historyRepo.AddPasswordHistory(&models.PasswordHistory{
+		UserID:       user.ID,
+		PasswordHash: user.Password,
+	})
+}
@@ -0,0 +1,199 @@
+package services_test
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"clean-arch/internal/core/services"
+	"clean-arch/internal/mailer"
+	"clean-arch/internal/mocks"
+	"clean-arch/internal/validation"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newPasswordService(userRepo *mocks.MockUserRepository, resetRepo *mocks.MockPasswordResetRepository, historyRepo *mocks.MockPasswordHistoryRepository, tokenRepo *mocks.MockRefreshTokenRepository, mail mailer.Mailer) *services.PasswordServiceImpl {
+	tokenService := services.NewTokenService(tokenRepo, repository.NewInMemoryRevocationStore(), userRepo, time.Minute, time.Hour)
+	return services.NewPasswordService(userRepo, resetRepo, historyRepo, tokenService, mail, "")
+}
+
+func hashPassword(t *testing.T, password string) string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	assert.NoError(t, err)
+	return string(hash)
+}
+
+func TestForgotPassword_UnknownEmail(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockResetRepo := new(mocks.MockPasswordResetRepository)
+	mockMailer := mailer.NewMemoryMailer()
+	passwordService := newPasswordService(mockUserRepo, mockResetRepo, new(mocks.MockPasswordHistoryRepository), new(mocks.MockRefreshTokenRepository), mockMailer)
+
+	mockUserRepo.On("FindUserByEmail", "nobody@gmail.com").Return(nil, models.ErrUserDoesNotExist)
+
+	err := passwordService.ForgotPassword(context.Background(), "nobody@gmail.com")
+
+	assert.NoError(t, err)
+	assert.NoError(t, passwordService.Wait(context.Background()))
+	assert.Empty(t, mockMailer.Messages())
+	mockResetRepo.AssertNotCalled(t, "CreatePasswordReset", mock.Anything)
+}
+
+func TestForgotPassword_SendsHashedToken(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockResetRepo := new(mocks.MockPasswordResetRepository)
+	mockMailer := mailer.NewMemoryMailer()
+	passwordService := newPasswordService(mockUserRepo, mockResetRepo, new(mocks.MockPasswordHistoryRepository), new(mocks.MockRefreshTokenRepository), mockMailer)
+
+	var stored *models.PasswordResetToken
+	mockUserRepo.On("FindUserByEmail", "johndoe@gmail.com").Return(&models.User{ID: 1, Email: "johndoe@gmail.com"}, nil)
+	mockResetRepo.On("InvalidateUserPasswordResets", 1, mock.AnythingOfType("time.Time")).Return(nil)
+	mockResetRepo.On("CreatePasswordReset", mock.AnythingOfType("*models.PasswordResetToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(0).(*models.PasswordResetToken) }).
+		Return(nil)
+
+	err := passwordService.ForgotPassword(context.Background(), "johndoe@gmail.com")
+
+	assert.NoError(t, err)
+	assert.NoError(t, passwordService.Wait(context.Background()))
+	assert.Len(t, mockMailer.Messages(), 1)
+	assert.Equal(t, 1, stored.UserID)
+	assert.NotContains(t, mockMailer.Messages()[0].Body, stored.TokenHash)
+	mockUserRepo.AssertExpectations(t)
+	mockResetRepo.AssertExpectations(t)
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(mailer.Message) error {
+	return errors.New("smtp unavailable")
+}
+
+func TestForgotPassword_SucceedsWhenMailFails(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockResetRepo := new(mocks.MockPasswordResetRepository)
+	passwordService := newPasswordService(mockUserRepo, mockResetRepo, new(mocks.MockPasswordHistoryRepository), new(mocks.MockRefreshTokenRepository), failingMailer{})
+
+	mockUserRepo.On("FindUserByEmail", "johndoe@gmail.com").Return(&models.User{ID: 1, Email: "johndoe@gmail.com"}, nil)
+	mockResetRepo.On("InvalidateUserPasswordResets", 1, mock.AnythingOfType("time.Time")).Return(nil)
+	mockResetRepo.On("CreatePasswordReset", mock.AnythingOfType("*models.PasswordResetToken")).Return(nil)
+
+	err := passwordService.ForgotPassword(context.Background(), "johndoe@gmail.com")
+
+	assert.NoError(t, err)
+	assert.NoError(t, passwordService.Wait(context.Background()))
+	mockResetRepo.AssertExpectations(t)
+}
+
+func TestResetPassword_Success(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockResetRepo := new(mocks.MockPasswordResetRepository)
+	mockTokenRepo := new(mocks.MockRefreshTokenRepository)
+	mockHistoryRepo := new(mocks.MockPasswordHistoryRepository)
+	passwordService := newPasswordService(mockUserRepo, mockResetRepo, mockHistoryRepo, mockTokenRepo, mailer.NewMemoryMailer())
+
+	reset := &models.PasswordResetToken{ID: 3, UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	mockResetRepo.On("FindPasswordResetByHash", mock.AnythingOfType("string")).Return(reset, nil)
+	mockResetRepo.On("MarkPasswordResetUsed", 3, mock.AnythingOfType("time.Time")).Return(true, nil)
+	mockUserRepo.On("FindUserByID", 1).Return(&models.User{ID: 1, Password: "old-hash"}, nil)
+	mockUserRepo.On("UpdateUser", mock.MatchedBy(func(user *models.User) bool {
+		return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("newpassword1")) == nil
+	}), []string{"password"}).Return(nil)
+	mockTokenRepo.On("RevokeUserRefreshTokens", 1, mock.AnythingOfType("time.Time")).Return(nil)
+	mockHistoryRepo.On("FindRecentPasswordHashes", 1, models.PasswordHistoryLimit).Return([]string{}, nil)
+	mockHistoryRepo.On("AddPasswordHistory", mock.AnythingOfType("*models.PasswordHistory")).Return(nil)
+
+	err := passwordService.ResetPassword(context.Background(), &models.ResetPasswordInput{Token: "reset-token", NewPassword: "newpassword1", Reenter: "newpassword1"})
+
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	mockResetRepo.AssertExpectations(t)
+	mockHistoryRepo.AssertExpectations(t)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestResetPassword_UsedToken(t *testing.T) {
+	mockResetRepo := new(mocks.MockPasswordResetRepository)
+	passwordService := newPasswordService(new(mocks.MockUserRepository), mockResetRepo, new(mocks.MockPasswordHistoryRepository), new(mocks.MockRefreshTokenRepository), mailer.NewMemoryMailer())
+
+	usedAt := time.Now().Add(-time.Minute)
+	reset := &models.PasswordResetToken{ID: 3, UserID: 1, ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt}
+	mockResetRepo.On("FindPasswordResetByHash", mock.AnythingOfType("string")).Return(reset, nil)
+
+	err := passwordService.ResetPassword(context.Background(), &models.ResetPasswordInput{Token: "reset-token", NewPassword: "newpassword1", Reenter: "newpassword1"})
+
+	assert.ErrorIs(t, err, models.ErrInvalidResetToken)
+	mockResetRepo.AssertExpectations(t)
+}
+
+func TestChangePassword_Success(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockHistoryRepo := new(mocks.MockPasswordHistoryRepository)
+	passwordService := newPasswordService(mockUserRepo, new(mocks.MockPasswordResetRepository), mockHistoryRepo, new(mocks.MockRefreshTokenRepository), mailer.NewMemoryMailer())
+
+	mockUserRepo.On("FindUserByID", 1).Return(&models.User{ID: 1, Password: hashPassword(t, "oldpassword1")}, nil)
+	mockHistoryRepo.On("FindRecentPasswordHashes", 1, models.PasswordHistoryLimit).Return([]string{hashPassword(t, "olderpassword1")}, nil)
+	mockUserRepo.On("UpdateUser", mock.AnythingOfType("*models.User"), mock.Anything).Return(nil)
+	mockHistoryRepo.On("AddPasswordHistory", mock.MatchedBy(func(entry *models.PasswordHistory) bool {
+		return entry.UserID == 1 && bcrypt.CompareHashAndPassword([]byte(entry.PasswordHash), []byte("newpassword1")) == nil
+	})).Return(nil)
+
+	err := passwordService.ChangePassword(context.Background(), 1, &models.PasswordReset{CurrentPassword: "oldpassword1", NewPassword: "newpassword1", Reenter: "newpassword1"})
+
+	assert.NoError(t, err)
+	mockUserRepo.AssertExpectations(t)
+	mockHistoryRepo.AssertExpectations(t)
+}
+
+func TestChangePassword_WrongCurrentPassword(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	passwordService := newPasswordService(mockUserRepo, new(mocks.MockPasswordResetRepository), new(mocks.MockPasswordHistoryRepository), new(mocks.MockRefreshTokenRepository), mailer.NewMemoryMailer())
+
+	mockUserRepo.On("FindUserByID", 1).Return(&models.User{ID: 1, Password: hashPassword(t, "oldpassword1")}, nil)
+
+	err := passwordService.ChangePassword(context.Background(), 1, &models.PasswordReset{CurrentPassword: "guessed-wrong", NewPassword: "newpassword1", Reenter: "newpassword1"})
+
+	assert.ErrorIs(t, err, models.ErrIncorrectPassword)
+	mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestChangePassword_RejectsRecentPassword(t *testing.T) {
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockHistoryRepo := new(mocks.MockPasswordHistoryRepository)
+	passwordService := newPasswordService(mockUserRepo, new(mocks.MockPasswordResetRepository), mockHistoryRepo, new(mocks.MockRefreshTokenRepository), mailer.NewMemoryMailer())
+
+	mockUserRepo.On("FindUserByID", 1).Return(&models.User{ID: 1, Password: hashPassword(t, "oldpassword1")}, nil)
+	mockHistoryRepo.On("FindRecentPasswordHashes", 1, models.PasswordHistoryLimit).Return([]string{hashPassword(t, "olderpassword1")}, nil)
+
+	err := passwordService.ChangePassword(context.Background(), 1, &models.PasswordReset{CurrentPassword: "oldpassword1", NewPassword: "olderpassword1", Reenter: "olderpassword1"})
+
+	assert.ErrorIs(t, err, models.ErrPasswordReused)
+	mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestChangePassword_ReportsPolicyViolations(t *testing.T) {
+	policy := validation.Default.PasswordPolicy()
+	validation.Default.SetPasswordPolicy(&models.PasswordPolicy{MinLength: 8, RequireDigit: true, RejectIdentity: true})
+	t.Cleanup(func() { validation.Default.SetPasswordPolicy(policy) })
+
+	mockUserRepo := new(mocks.MockUserRepository)
+	passwordService := newPasswordService(mockUserRepo, new(mocks.MockPasswordResetRepository), new(mocks.MockPasswordHistoryRepository), new(mocks.MockRefreshTokenRepository), mailer.NewMemoryMailer())
+
+	mockUserRepo.On("FindUserByID", 1).Return(&models.User{ID: 1, UserName: "JohnDoe", Email: "john@example.com", Password: hashPassword(t, "oldpassword1")}, nil)
+
+	err := passwordService.ChangePassword(context.Background(), 1, &models.PasswordReset{CurrentPassword: "oldpassword1", NewPassword: "ImJohnDoe!", Reenter: "ImJohnDoe!"})
+
+	var domainErr *models.Error
+	assert.ErrorAs(t, err, &domainErr)
+	assert.Equal(t, models.CodeValidation, domainErr.Code)
+	assert.Equal(t, []models.FieldError{
+		{Field: "new_password", Message: "new_password must contain a digit", Rule: "password_digit"},
+		{Field: "new_password", Message: "new_password must not contain the user name or email address", Rule: "password_identity"},
+	}, domainErr.Fields)
+	mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+type RoleService interface {
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	ListPermissions(ctx context.Context) ([]models.Permission, error)
+	CreateRole(ctx context.Context, input *models.CreateRoleInput) (*models.Role, error)
+	SetRolePermissions(ctx context.Context, name string, permissions []string) (*models.Role, error)
+	DeleteRole(ctx context.Context, name string) error
+	AssignRole(ctx context.Context, userID int, name string) error
+	RevokeRole(ctx context.Context, actorID, userID int, name string) error
+	UserAccess(ctx context.Context, user *models.User) (*models.UserAccess, error)
+	SeedDefaults(ctx context.Context) error
+}
+
+type RoleServiceImpl struct {
+	roleRepo     repository.RoleRepository
+	userRepo     repository.UserRespository
+	tokenService TokenService
+}
+
+func NewRoleService(roleRepo repository.RoleRepository, userRepo repository.UserRespository, tokenService TokenService) *RoleServiceImpl {
+	return &RoleServiceImpl{
+		roleRepo:     roleRepo,
+		userRepo:     userRepo,
+		tokenService: tokenService,
+	}
+}
+
+// SeedDefaults makes sure every known permission and the built-in roles
+// exist. The admin role is reset to all permissions on every start.
+func (s *RoleServiceImpl) SeedDefaults(ctx context.Context) error {
+	permissions := append([]models.Permission(nil), models.Permissions...)
+	if err := s.roleRepo.SavePermissions(permissions); err != nil {
+		return err
+	}
+
+	for _, name := range []string{models.RoleUser, models.RoleAdmin} {
+		role, err := s.roleRepo.FindRoleByName(name)
+		created := false
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			role = &models.Role{Name: name, Description: "Built-in role"}
+			if err := s.roleRepo.CreateRole(role); err != nil {
+				return err
+			}
+			created = true
+		} else if err != nil {
+			return err
+		}
+
+		if !created && name != models.RoleAdmin {
+			continue
+		}
+
+		granted, err := s.resolvePermissions(models.DefaultRolePermissions[name])
+		if err != nil {
+			return err
+		}
+		if err := s.roleRepo.SetRolePermissions(role, granted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RoleServiceImpl) ListRoles(ctx context.Context) ([]models.Role, error) {
+	return s.roleRepo.ListRoles()
+}
+
+func (s *RoleServiceImpl) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	return s.roleRepo.ListPermissions()
+}
+
+func (s *RoleServiceImpl) CreateRole(ctx context.Context, input *models.CreateRoleInput) (*models.Role, error) {
+	if !roleNamePattern.MatchString(input.Name) {
+		return nil, models.ErrInvalidRoleName
+	}
+
+	if _, err := s.roleRepo.FindRoleByName(input.Name); err == nil {
+		return nil, models.ErrRoleAlreadyExists
+	} else if !errors.Is(err, repository.ErrRoleNotFound) {
+		return nil, err
+	}
+
+	permissions, err := s.resolvePermissions(input.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{
+		Name:        input.Name,
+		Description: input.Description,
+		Permissions: permissions,
+	}
+	if err := s.roleRepo.CreateRole(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// SetRolePermissions replaces the permissions of a role. When one is taken
+// away, the sessions of everyone holding the role are ended, since their
+// tokens still carry it.
+func (s *RoleServiceImpl) SetRolePermissions(ctx context.Context, name string, names []string) (*models.Role, error) {
+	if name == models.RoleAdmin {
+		return nil, models.ErrBuiltinRole
+	}
+
+	role, err := s.findRole(name)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.resolvePermissions(names)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		kept[permission.Name] = true
+	}
+	removed := false
+	for _, permission := range role.Permissions {
+		if !kept[permission.Name] {
+			removed = true
+			break
+		}
+	}
+
+	var holders []int
+	if removed {
+		if holders, err = s.roleRepo.FindRoleUserIDs(role); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.roleRepo.SetRolePermissions(role, permissions); err != nil {
+		return nil, err
+	}
+	role.Permissions = permissions
+	return role, s.logoutAll(ctx, holders)
+}
+
+// DeleteRole removes a role and ends the sessions of everyone who held it.
+func (s *RoleServiceImpl) DeleteRole(ctx context.Context, name string) error {
+	if _, builtin := models.DefaultRolePermissions[name]; builtin {
+		return models.ErrBuiltinRole
+	}
+
+	role, err := s.findRole(name)
+	if err != nil {
+		return err
+	}
+
+	holders, err := s.roleRepo.FindRoleUserIDs(role)
+	if err != nil {
+		return err
+	}
+	if err := s.roleRepo.DeleteRole(role); err != nil {
+		return err
+	}
+	return s.logoutAll(ctx, holders)
+}
+
+// AssignRole grants a role to a user. Assigning a role the user already has
+// is a no-op. The new permissions apply from the next login or refresh.
+func (s *RoleServiceImpl) AssignRole(ctx context.Context, userID int, name string) error {
+	user, err := s.userRepo.FindUserByID(ctx, userID)
+	if err != nil {
+		return models.ErrUserDoesNotExist
+	}
+
+	role, err := s.findRole(name)
+	if err != nil {
+		return err
+	}
+
+	return s.roleRepo.AssignUserRole(user.ID, role)
+}
+
+// RevokeRole takes a role away from a user and ends their sessions so the
+// lost permissions cannot be used until the current access token expires.
+func (s *RoleServiceImpl) RevokeRole(ctx context.Context, actorID, userID int, name string) error {
+	if actorID == userID {
+		return models.ErrCannotRevokeOwnRole
+	}
+
+	user, err := s.userRepo.FindUserByID(ctx, userID)
+	if err != nil {
+		return models.ErrUserDoesNotExist
+	}
+
+	role, err := s.findRole(name)
+	if err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.RemoveUserRole(user.ID, role); err != nil {
+		return err
+	}
+
+	return s.tokenService.LogoutAll(ctx, user.ID)
+}
+
+// UserAccess resolves the roles and permissions to sign into an access token
+// from the roles assigned to the user.
+func (s *RoleServiceImpl) UserAccess(ctx context.Context, user *models.User) (*models.UserAccess, error) {
+	roles, err := s.roleRepo.FindUserRoles(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	access := &models.UserAccess{}
+	granted := make(map[string]bool)
+	for _, role := range roles {
+		access.Roles = append(access.Roles, role.Name)
+		for _, permission := range role.Permissions {
+			if !granted[permission.Name] {
+				granted[permission.Name] = true
+				access.Permissions = append(access.Permissions, permission.Name)
+			}
+		}
+	}
+	sort.Strings(access.Roles)
+	sort.Strings(access.Permissions)
+	return access, nil
+}
+
+func (s *RoleServiceImpl) logoutAll(ctx context.Context, userIDs []int) error {
+	for _, userID := range userIDs {
+		if err := s.tokenService.LogoutAll(ctx, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RoleServiceImpl) findRole(name string) (*models.Role, error) {
+	role, err := s.roleRepo.FindRoleByName(name)
+	if errors.Is(err, repository.ErrRoleNotFound) {
+		return nil, models.ErrRoleNotFound
+	}
+	return role, err
+}
+
+// resolvePermissions looks up permissions by name, rejecting unknown ones.
+func (s *RoleServiceImpl) resolvePermissions(names []string) ([]models.Permission, error) {
+	if len(names) == 0 {
+		return []models.Permission{}, nil
+	}
+
+	known, err := s.roleRepo.ListPermissions()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]models.Permission, len(known))
+	for _, permission := range known {
+		byName[permission.Name] = permission
+	}
+
+	permissions := make([]models.Permission, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		permission, ok := byName[name]
+		if !ok {
+			return nil, models.ErrUnknownPermission
+		}
+		if !seen[name] {
+			seen[name] = true
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions, nil
+}
@@ -0,0 +1,146 @@
+package services_test
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"clean-arch/internal/core/services"
+	"clean-arch/internal/mocks"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newRoleService(roleRepo *mocks.MockRoleRepository, userRepo *mocks.MockUserRepository, tokenRepo *mocks.MockRefreshTokenRepository) *services.RoleServiceImpl {
+	tokenService := services.NewTokenService(tokenRepo, repository.NewInMemoryRevocationStore(), userRepo, time.Minute, time.Hour)
+	return services.NewRoleService(roleRepo, userRepo, tokenService)
+}
+
+func TestUserAccess_UsesAssignedRolesOnly(t *testing.T) {
+	mockRoleRepo := new(mocks.MockRoleRepository)
+	roleService := newRoleService(mockRoleRepo, new(mocks.MockUserRepository), new(mocks.MockRefreshTokenRepository))
+
+	mockRoleRepo.On("FindUserRoles", 1).Return([]models.Role{
+		{Name: "support", Permissions: []models.Permission{{Name: models.PermUsersRead}, {Name: models.PermUsersLogout}}},
+		{Name: models.RoleUser},
+	}, nil)
+
+	access, err := roleService.UserAccess(context.Background(), &models.User{ID: 1, Role: models.RoleAdmin})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"support", "user"}, access.Roles)
+	assert.Equal(t, []string{models.PermUsersLogout, models.PermUsersRead}, access.Permissions)
+	mockRoleRepo.AssertNotCalled(t, "FindRoleByName", mock.Anything)
+}
+
+func TestCreateRole_RejectsUnknownPermission(t *testing.T) {
+	mockRoleRepo := new(mocks.MockRoleRepository)
+	roleService := newRoleService(mockRoleRepo, new(mocks.MockUserRepository), new(mocks.MockRefreshTokenRepository))
+
+	mockRoleRepo.On("FindRoleByName", "support").Return(nil, repository.ErrRoleNotFound)
+	mockRoleRepo.On("ListPermissions").Return([]models.Permission{{ID: 1, Name: models.PermUsersRead}}, nil)
+
+	role, err := roleService.CreateRole(context.Background(), &models.CreateRoleInput{Name: "support", Permissions: []string{"users:delete"}})
+
+	assert.Nil(t, role)
+	assert.ErrorIs(t, err, models.ErrUnknownPermission)
+	mockRoleRepo.AssertNotCalled(t, "CreateRole", mock.Anything)
+}
+
+func TestDeleteRole_RejectsBuiltinRole(t *testing.T) {
+	mockRoleRepo := new(mocks.MockRoleRepository)
+	roleService := newRoleService(mockRoleRepo, new(mocks.MockUserRepository), new(mocks.MockRefreshTokenRepository))
+
+	err := roleService.DeleteRole(context.Background(), models.RoleAdmin)
+
+	assert.ErrorIs(t, err, models.ErrBuiltinRole)
+	mockRoleRepo.AssertNotCalled(t, "DeleteRole", mock.Anything)
+}
+
+func TestSetRolePermissions_EndsHolderSessionsWhenRemoving(t *testing.T) {
+	mockRoleRepo := new(mocks.MockRoleRepository)
+	mockTokenRepo := new(mocks.MockRefreshTokenRepository)
+	roleService := newRoleService(mockRoleRepo, new(mocks.MockUserRepository), mockTokenRepo)
+
+	read := models.Permission{ID: 1, Name: models.PermUsersRead}
+	block := models.Permission{ID: 2, Name: models.PermUsersBlock}
+	support := &models.Role{ID: 3, Name: "support", Permissions: []models.Permission{read, block}}
+	mockRoleRepo.On("FindRoleByName", "support").Return(support, nil)
+	mockRoleRepo.On("ListPermissions").Return([]models.Permission{read, block}, nil)
+	mockRoleRepo.On("FindRoleUserIDs", support).Return([]int{4, 5}, nil)
+	mockRoleRepo.On("SetRolePermissions", support, []models.Permission{read}).Return(nil)
+	mockTokenRepo.On("RevokeUserRefreshTokens", 4, mock.AnythingOfType("time.Time")).Return(nil)
+	mockTokenRepo.On("RevokeUserRefreshTokens", 5, mock.AnythingOfType("time.Time")).Return(nil)
+
+	role, err := roleService.SetRolePermissions(context.Background(), "support", []string{models.PermUsersRead})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Permission{read}, role.Permissions)
+	mockRoleRepo.AssertExpectations(t)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestSetRolePermissions_KeepsSessionsWhenAdding(t *testing.T) {
+	mockRoleRepo := new(mocks.MockRoleRepository)
+	mockTokenRepo := new(mocks.MockRefreshTokenRepository)
+	roleService := newRoleService(mockRoleRepo, new(mocks.MockUserRepository), mockTokenRepo)
+
+	read := models.Permission{ID: 1, Name: models.PermUsersRead}
+	block := models.Permission{ID: 2, Name: models.PermUsersBlock}
+	support := &models.Role{ID: 3, Name: "support", Permissions: []models.Permission{read}}
+	mockRoleRepo.On("FindRoleByName", "support").Return(support, nil)
+	mockRoleRepo.On("ListPermissions").Return([]models.Permission{read, block}, nil)
+	mockRoleRepo.On("SetRolePermissions", support, []models.Permission{read, block}).Return(nil)
+
+	_, err := roleService.SetRolePermissions(context.Background(), "support", []string{models.PermUsersRead, models.PermUsersBlock})
+
+	assert.NoError(t, err)
+	mockRoleRepo.AssertNotCalled(t, "FindRoleUserIDs", mock.Anything)
+	mockTokenRepo.AssertNotCalled(t, "RevokeUserRefreshTokens", mock.Anything, mock.Anything)
+}
+
+func TestDeleteRole_EndsHolderSessions(t *testing.T) {
+	mockRoleRepo := new(mocks.MockRoleRepository)
+	mockTokenRepo := new(mocks.MockRefreshTokenRepository)
+	roleService := newRoleService(mockRoleRepo, new(mocks.MockUserRepository), mockTokenRepo)
+
+	support := &models.Role{ID: 3, Name: "support"}
+	mockRoleRepo.On("FindRoleByName", "support").Return(support, nil)
+	mockRoleRepo.On("FindRoleUserIDs", support).Return([]int{4}, nil)
+	mockRoleRepo.On("DeleteRole", support).Return(nil)
+	mockTokenRepo.On("RevokeUserRefreshTokens", 4, mock.AnythingOfType("time.Time")).Return(nil)
+
+	assert.NoError(t, roleService.DeleteRole(context.Background(), "support"))
+	mockRoleRepo.AssertExpectations(t)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestRevokeRole_EndsSessions(t *testing.T) {
+	mockRoleRepo := new(mocks.MockRoleRepository)
+	mockUserRepo := new(mocks.MockUserRepository)
+	mockTokenRepo := new(mocks.MockRefreshTokenRepository)
+	roleService := newRoleService(mockRoleRepo, mockUserRepo, mockTokenRepo)
+
+	admin := &models.Role{ID: 2, Name: models.RoleAdmin}
+	mockUserRepo.On("FindUserByID", 2).Return(&models.User{ID: 2, Role: models.RoleAdmin}, nil)
+	mockRoleRepo.On("FindRoleByName", models.RoleAdmin).Return(admin, nil)
+	mockRoleRepo.On("RemoveUserRole", 2, admin).Return(nil)
+	mockTokenRepo.On("RevokeUserRefreshTokens", 2, mock.AnythingOfType("time.Time")).Return(nil)
+
+	err := roleService.RevokeRole(context.Background(), 1, 2, models.RoleAdmin)
+
+	assert.NoError(t, err)
+	mockRoleRepo.AssertExpectations(t)
+	mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestRevokeRole_CannotRevokeOwnRole(t *testing.T) {
+	roleService := newRoleService(new(mocks.MockRoleRepository), new(mocks.MockUserRepository), new(mocks.MockRefreshTokenRepository))
+
+	err := roleService.RevokeRole(context.Background(), 1, 1, models.RoleAdmin)
+
+	assert.ErrorIs(t, err, models.ErrCannotRevokeOwnRole)
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+const DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+type TokenService interface {
+	IssueRefreshToken(ctx context.Context, userID int) (string, error)
+	RotateRefreshToken(ctx context.Context, refreshToken string) (*models.User, string, error)
+	Logout(ctx context.Context, userID int, jti string, expiresAt time.Time, refreshToken string) error
+	LogoutAll(ctx context.Context, userID int) error
+	PurgeExpired(ctx context.Context) error
+}
+
+type TokenServiceImpl struct {
+	tokenRepo   repository.RefreshTokenRepository
+	revocations repository.RevocationStore
+	userRepo    repository.UserRespository
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+}
+
+// NewTokenService wires refresh token and revocation storage. accessTTL must
+// be at least the lifetime of issued access tokens: "log out all devices"
+// keeps its revocation entry around for that long.
+func NewTokenService(tokenRepo repository.RefreshTokenRepository, revocations repository.RevocationStore, userRepo repository.UserRespository, accessTTL, refreshTTL time.Duration) *TokenServiceImpl {
+	if refreshTTL <= 0 {
+		refreshTTL = DefaultRefreshTokenTTL
+	}
+	return &TokenServiceImpl{
+		tokenRepo:   tokenRepo,
+		revocations: revocations,
+		userRepo:    userRepo,
+		accessTTL:   accessTTL,
+		refreshTTL:  refreshTTL,
+	}
+}
+
+// IssueRefreshToken starts a new token family for the user, typically at login.
+func (s *TokenServiceImpl) IssueRefreshToken(ctx context.Context, userID int) (string, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	return s.issue(userID, familyID)
+}
+
+// RotateRefreshToken consumes a refresh token and returns its owner together
+// with a replacement from the same family. Presenting a token that was already
+// consumed revokes the whole family, since it means the token was leaked.
+func (s *TokenServiceImpl) RotateRefreshToken(ctx context.Context, refreshToken string) (*models.User, string, error) {
+	stored, err := s.tokenRepo.FindRefreshTokenByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, "", models.ErrInvalidRefreshToken
+	}
+
+	now := time.Now()
+	if stored.RevokedAt != nil || now.After(stored.ExpiresAt) {
+		return nil, "", models.ErrInvalidRefreshToken
+	}
+
+	if stored.UsedAt != nil {
+		return nil, "", s.revokeFamily(stored.FamilyID, now)
+	}
+
+	marked, err := s.tokenRepo.MarkRefreshTokenUsed(stored.ID, now)
+	if err != nil {
+		return nil, "", err
+	}
+	if !marked {
+		return nil, "", s.revokeFamily(stored.FamilyID, now)
+	}
+
+	user, err := s.userRepo.FindUserByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, "", models.ErrInvalidRefreshToken
+	}
+	if user.Status == models.StatusBlocked {
+		return nil, "", models.ErrUserBlocked
+	}
+
+	newToken, err := s.issue(stored.UserID, stored.FamilyID)
+	if err != nil {
+		return nil, "", err
+	}
+	user.Password = ""
+
+	return user, newToken, nil
+}
+
+// Logout revokes the presented access token and, when given, the refresh
+// token family it belongs to.
+func (s *TokenServiceImpl) Logout(ctx context.Context, userID int, jti string, expiresAt time.Time, refreshToken string) error {
+	if jti != "" {
+		if err := s.revocations.RevokeToken(jti, userID, expiresAt); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := s.tokenRepo.FindRefreshTokenByHash(hashToken(refreshToken))
+	if err != nil || stored.UserID != userID {
+		return nil
+	}
+	return s.tokenRepo.RevokeRefreshTokenFamily(stored.FamilyID, time.Now())
+}
+
+// LogoutAll ends every session of the user: all refresh tokens are revoked and
+// access tokens issued up to now are rejected until they would have expired.
+func (s *TokenServiceImpl) LogoutAll(ctx context.Context, userID int) error {
+	now := time.Now()
+	if err := s.tokenRepo.RevokeUserRefreshTokens(userID, now); err != nil {
+		return err
+	}
+	return s.revocations.RevokeUserTokens(userID, now, now.Add(s.accessTTL))
+}
+
+// PurgeExpired garbage-collects revocation entries and refresh tokens that can
+// no longer be presented.
+func (s *TokenServiceImpl) PurgeExpired(ctx context.Context) error {
+	now := time.Now()
+	if err := s.revocations.DeleteExpired(now); err != nil {
+		return err
+	}
+	return s.tokenRepo.DeleteExpiredRefreshTokens(now)
+}
+
+func (s *TokenServiceImpl) issue(userID int, familyID string) (string, error) {
+	raw, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	token := &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}
+	if err := s.tokenRepo.CreateRefreshToken(token); err != nil {
+		return "", err
+	}
+
+	return raw, nil
+}
+
+func (s *TokenServiceImpl) revokeFamily(familyID string, now time.Time) error {
+	if err := s.tokenRepo.RevokeRefreshTokenFamily(familyID, now); err != nil {
+		return err
+	}
+	return models.ErrRefreshTokenReused
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,134 @@
+package services_test
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/core/repository"
+	"clean-arch/internal/core/services"
+	"clean-arch/internal/mocks"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIssueRefreshToken(t *testing.T) {
+	mockTokenRepo := new(mocks.MockRefreshTokenRepository)
+	tokenService := services.NewTokenService(mockTokenRepo, repository.NewInMemoryRevocationStore(), new(mocks.MockUserRepository), time.Minute, time.Hour)
+
+	var stored *models.RefreshToken
+	mockTokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*models.RefreshToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(0).(*models.RefreshToken) }).
+		Return(nil)
+
+	token, err := tokenService.IssueRefreshToken(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, 1, stored.UserID)
+	assert.NotEmpty(t, stored.FamilyID)
+	assert.NotEqual(t, token, stored.TokenHash)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestRotateRefreshToken_Success(t *testing.T) {
+	mockTokenRepo := new(mocks.MockRefreshTokenRepository)
+	mockUserRepo := new(mocks.MockUserRepository)
+	tokenService := services.NewTokenService(mockTokenRepo, repository.NewInMemoryRevocationStore(), mockUserRepo, time.Minute, time.Hour)
+
+	stored := &models.RefreshToken{ID: 7, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)}
+	mockTokenRepo.On("FindRefreshTokenByHash", mock.AnythingOfType("string")).Return(stored, nil)
+	mockTokenRepo.On("MarkRefreshTokenUsed", 7, mock.AnythingOfType("time.Time")).Return(true, nil)
+	mockTokenRepo.On("CreateRefreshToken", mock.MatchedBy(func(token *models.RefreshToken) bool {
+		return token.FamilyID == "family" && token.UserID == 1
+	})).Return(nil)
+	mockUserRepo.On("FindUserByID", 1).Return(&models.User{ID: 1, Email: "johndoe@gmail.com", Password: "hash", Status: "Active"}, nil)
+
+	user, newToken, err := tokenService.RotateRefreshToken(context.Background(), "old-token")
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, newToken)
+	assert.NotEqual(t, "old-token", newToken)
+	assert.Equal(t, "johndoe@gmail.com", user.Email)
+	assert.Empty(t, user.Password)
+	mockTokenRepo.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestRotateRefreshToken_ReuseRevokesFamily(t *testing.T) {
+	mockTokenRepo := new(mocks.MockRefreshTokenRepository)
+	tokenService := services.NewTokenService(mockTokenRepo, repository.NewInMemoryRevocationStore(), new(mocks.MockUserRepository), time.Minute, time.Hour)
+
+	usedAt := time.Now().Add(-time.Minute)
+	stored := &models.RefreshToken{ID: 7, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt}
+	mockTokenRepo.On("FindRefreshTokenByHash", mock.AnythingOfType("string")).Return(stored, nil)
+	mockTokenRepo.On("RevokeRefreshTokenFamily", "family", mock.AnythingOfType("time.Time")).Return(nil)
+
+	user, newToken, err := tokenService.RotateRefreshToken(context.Background(), "replayed-token")
+
+	assert.ErrorIs(t, err, models.ErrRefreshTokenReused)
+	assert.Nil(t, user)
+	assert.Empty(t, newToken)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestRotateRefreshToken_Expired(t *testing.T) {
+	mockTokenRepo := new(mocks.MockRefreshTokenRepository)
+	tokenService := services.NewTokenService(mockTokenRepo, repository.NewInMemoryRevocationStore(), new(mocks.MockUserRepository), time.Minute, time.Hour)
+
+	stored := &models.RefreshToken{ID: 7, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(-time.Hour)}
+	mockTokenRepo.On("FindRefreshTokenByHash", mock.AnythingOfType("string")).Return(stored, nil)
+
+	_, _, err := tokenService.RotateRefreshToken(context.Background(), "expired-token")
+
+	assert.ErrorIs(t, err, models.ErrInvalidRefreshToken)
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestLogoutAll_RevokesEverySession(t *testing.T) {
+	mockTokenRepo := new(mocks.MockRefreshTokenRepository)
+	revocations := repository.NewInMemoryRevocationStore()
+	tokenService := services.NewTokenService(mockTokenRepo, revocations, new(mocks.MockUserRepository), time.Minute, time.Hour)
+
+	mockTokenRepo.On("RevokeUserRefreshTokens", 1, mock.AnythingOfType("time.Time")).Return(nil)
+	issuedAt := time.Now().Add(-time.Second)
+
+	err := tokenService.LogoutAll(context.Background(), 1)
+	assert.NoError(t, err)
+
+	revoked, err := revocations.IsRevoked("any-token", 1, issuedAt)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = revocations.IsRevoked("any-token", 2, issuedAt)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.NoError(t, revocations.DeleteExpired(time.Now().Add(2*time.Minute)))
+	revoked, err = revocations.IsRevoked("any-token", 1, issuedAt)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	mockTokenRepo.AssertExpectations(t)
+}
+
+func TestLogoutAll_KeepsTokensIssuedAfterwards(t *testing.T) {
+	mockTokenRepo := new(mocks.MockRefreshTokenRepository)
+	revocations := repository.NewInMemoryRevocationStore()
+	tokenService := services.NewTokenService(mockTokenRepo, revocations, new(mocks.MockUserRepository), time.Minute, time.Hour)
+
+	mockTokenRepo.On("RevokeUserRefreshTokens", 1, mock.AnythingOfType("time.Time")).Return(nil)
+	before := time.Now()
+
+	assert.NoError(t, tokenService.LogoutAll(context.Background(), 1))
+	after := time.Now()
+
+	revoked, err := revocations.IsRevoked("any-token", 1, before)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = revocations.IsRevoked("any-token", 1, after)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
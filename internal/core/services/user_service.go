@@ -3,69 +3,457 @@ package services
 import (
 	"clean-arch/internal/core/models"
 	"clean-arch/internal/core/repository"
+	"clean-arch/internal/logger"
+	"clean-arch/internal/mailer"
+	"clean-arch/internal/storage"
+	"clean-arch/internal/tracing"
+	"clean-arch/internal/validation"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type UserService interface {
-	SignUp(user *models.SignupInput) error
-	Login(email, password string) (*models.User, error)
-	GetProfile(userID int) (*models.User, error)
+	SignUp(ctx context.Context, user *models.SignupInput) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	Login(ctx context.Context, email, password string) (*models.User, error)
+	GetProfile(ctx context.Context, userID int) (*models.User, error)
+	UpdateProfile(ctx context.Context, userID int, input *models.UpdateProfileInput) (*models.User, error)
+	UploadProfilePicture(ctx context.Context, userID int, file io.Reader) (*models.User, error)
+	DeleteAccount(ctx context.Context, userID int) error
+	PurgeDeletedAccounts(ctx context.Context) (int64, error)
+	PurgeExpiredRegistrations(ctx context.Context) (int64, error)
+}
+
+type UserServiceConfig struct {
+	Verification VerificationConfig
+	// DeletionGracePeriod is how long a deleted account can be restored by
+	// logging in again before it is purged.
+	DeletionGracePeriod time.Duration
+	// PurgeMode is models.PurgeModeDelete or models.PurgeModeAnonymize.
+	PurgeMode string
+	// ConcealSignupConflicts makes SignUp succeed for emails that already
+	// have an account and mail the owner instead, so the response does not
+	// reveal whether the email is registered.
+	ConcealSignupConflicts bool
 }
 
 type UserServiceImpl struct {
-	userRepo repository.UserRespository
+	userRepo     repository.UserRespository
+	pendingRepo  repository.PendingUserRepository
+	mailer       mailer.Mailer
+	blobStore    storage.BlobStore
+	verification VerificationConfig
+	gracePeriod  time.Duration
+	purgeMode    string
+	conceal      bool
 }
 
-func NewUserService(userRepo repository.UserRespository) *UserServiceImpl {
-	return &UserServiceImpl{userRepo: userRepo}
+func NewUserService(userRepo repository.UserRespository, pendingRepo repository.PendingUserRepository, mailer mailer.Mailer, blobStore storage.BlobStore, config UserServiceConfig) *UserServiceImpl {
+	if config.Verification.TTL <= 0 {
+		config.Verification.TTL = models.VerificationTokenTTL
+	}
+	if config.DeletionGracePeriod <= 0 {
+		config.DeletionGracePeriod = models.DefaultDeletionGracePeriod
+	}
+	if config.PurgeMode == "" {
+		config.PurgeMode = models.PurgeModeDelete
+	}
+	return &UserServiceImpl{
+		userRepo:     userRepo,
+		pendingRepo:  pendingRepo,
+		mailer:       mailer,
+		blobStore:    blobStore,
+		verification: config.Verification,
+		gracePeriod:  config.DeletionGracePeriod,
+		purgeMode:    config.PurgeMode,
+		conceal:      config.ConcealSignupConflicts,
+	}
 }
 
-func (s *UserServiceImpl) SignUp(user *models.SignupInput) error {
-	exists, _ := s.userRepo.FindUserByEmail(user.Email)
+// SignUp parks the registration as a pending user and emails a verification
+// token. The account only becomes usable once VerifyEmail succeeds.
+func (s *UserServiceImpl) SignUp(ctx context.Context, user *models.SignupInput) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.SignUp")
+	defer tracing.End(span, &err)
+
+	// Invalid input is rejected before the email is looked up, so the
+	// answer is the same whether or not the email is registered.
+	if err := validation.Struct(validation.WithIdentities(ctx, user.UserName, user.Email), user); err != nil {
+		return err
+	}
+
+	exists, _ := s.userRepo.FindUserByEmail(ctx, user.Email)
+	if exists == nil {
+		// A deleted account keeps its email until it is purged, so it can
+		// still be restored by logging in.
+		if deleted, _ := s.userRepo.FindDeletedUserByEmail(ctx, user.Email); deleted != nil && s.restorable(deleted) {
+			exists = deleted
+		}
+	}
 	if exists != nil {
+		if s.conceal {
+			return s.concealSignupConflict(ctx, user)
+		}
 		return models.ErrUserAlreadyExists
 	}
 
-	if err := models.ValidateSignup(*user); err != nil {
-		return errors.New(err.Error())
-
+	hashedPassword, err := hashPassword(ctx, user.Password)
+	if err != nil {
+		return err
 	}
 
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-
-	newUser := &models.User{
+	pending := &models.TempUser{
 		UserName:    user.UserName,
 		Email:       user.Email,
 		Password:    string(hashedPassword),
 		PhoneNumber: user.PhoneNumber,
-		Status:      "Active",
 	}
 
-	if err := s.userRepo.CreateUser(newUser); err != nil {
+	if err := s.sendVerification(pending); err != nil {
 		return err
 	}
+	logger.FromContext(ctx).Info("Registration pending verification")
+	return nil
+}
+
+func (s *UserServiceImpl) VerifyEmail(ctx context.Context, token string) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.VerifyEmail")
+	defer tracing.End(span, &err)
+
+	email, ok := parseEmailToken(s.verification.Secret, token, time.Now())
+	if !ok {
+		return models.ErrInvalidVerificationToken
+	}
+
+	pending, err := s.pendingRepo.FindPendingUserByEmail(email)
+	if err != nil {
+		return s.confirmEmailChange(ctx, email, token)
+	}
+
+	// Only the most recently sent token is accepted.
+	if pending.TokenHash != hashToken(token) {
+		return models.ErrInvalidVerificationToken
+	}
+
+	if exists, _ := s.userRepo.FindUserByEmail(ctx, email); exists != nil {
+		return models.ErrUserAlreadyExists
+	}
 
+	user, err := s.pendingRepo.PromotePendingUser(pending)
+	if err != nil {
+		return err
+	}
+	logger.FromContext(ctx).WithFields(logger.Fields{"user_id": user.ID}).Info("Email verified, account created")
 	return nil
+}
+
+// ResendVerification issues a fresh token for a pending registration. Unknown
+// or already verified emails are ignored so callers cannot probe for accounts.
+func (s *UserServiceImpl) ResendVerification(ctx context.Context, email string) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.ResendVerification")
+	defer tracing.End(span, &err)
+
+	pending, err := s.pendingRepo.FindPendingUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, repository.ErrPendingUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return s.sendVerification(pending)
+}
+
+// Login checks the credentials. Until the password is proven every failure is
+// ErrInvalidCredentials, and unknown emails cost a bcrypt comparison like
+// known ones, so neither the response nor its timing reveals whether an
+// account exists. Only then does it report an unverified email or a blocked
+// account.
+func (s *UserServiceImpl) Login(ctx context.Context, email, password string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.Login")
+	defer tracing.End(span, &err)
+
+	user, err := s.userRepo.FindUserByEmail(ctx, email)
+	restore := false
+	if err != nil {
+		if pending, _ := s.pendingRepo.FindPendingUserByEmail(email); pending != nil {
+			if err := comparePassword(ctx, pending.Password, password); err != nil {
+				return nil, models.ErrInvalidCredentials
+			}
+			return nil, models.ErrEmailNotVerified
+		}
+
+		user, err = s.userRepo.FindDeletedUserByEmail(ctx, email)
+		if err != nil || !s.restorable(user) {
+			comparePassword(ctx, dummyPasswordHash(), password)
+			return nil, models.ErrInvalidCredentials
+		}
+		restore = true
+	}
+
+	if err := comparePassword(ctx, user.Password, password); err != nil {
+		return nil, models.ErrInvalidCredentials
+	}
+	if user.Status == models.StatusBlocked {
+		return nil, models.ErrUserBlocked
+	}
+
+	// Logging in during the grace period cancels a pending account deletion.
+	// With two-factor authentication on, MFAService.Verify restores the
+	// account once the second step succeeds.
+	if restore && !user.MFAEnabled {
+		if err := s.userRepo.RestoreUser(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		user.DeletedAt = gorm.DeletedAt{}
+		logger.FromContext(ctx).WithFields(logger.Fields{"user_id": user.ID}).Info("Restored deleted account on login")
+	}
+	user.Password = ""
+
+	return user, nil
 
 }
 
-func (s *UserServiceImpl) Login(email, password string) (*models.User, error) {
-	user, err := s.userRepo.FindUserByEmail(email)
+func (s *UserServiceImpl) GetProfile(ctx context.Context, userID int) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetProfile")
+	defer tracing.End(span, &err)
+
+	return s.userRepo.FindUserByID(ctx, userID)
+}
+
+// UpdateProfile applies a partial update. A new email address is not applied
+// right away: it is parked on the user and a verification token is mailed to
+// it, and VerifyEmail swaps it in.
+func (s *UserServiceImpl) UpdateProfile(ctx context.Context, userID int, input *models.UpdateProfileInput) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateProfile")
+	defer tracing.End(span, &err)
+
+	user, err := s.userRepo.FindUserByID(ctx, userID)
 	if err != nil {
-		return nil, models.ErrUserDoesNotExist
+		return nil, err
+	}
+
+	if input.UserName != nil {
+		user.UserName = strings.TrimSpace(*input.UserName)
+	}
+	if input.PhoneNumber != nil {
+		user.PhoneNumber = *input.PhoneNumber
+	}
+
+	var token string
+	var expiresAt time.Time
+	if input.Email != nil && *input.Email != user.Email {
+		if err := s.ensureEmailAvailable(ctx, *input.Email); err != nil {
+			return nil, err
+		}
+
+		expiresAt = time.Now().Add(s.verification.TTL)
+		token = signEmailToken(s.verification.Secret, *input.Email, expiresAt)
+		user.PendingEmail = *input.Email
+		user.PendingEmailTokenHash = hashToken(token)
+	}
+
+	if err := s.userRepo.UpdateUser(ctx, user, "user_name", "phone_number", "pending_email", "pending_email_token_hash"); err != nil {
+		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, errors.New("invalid password")
+	if token != "" {
+		if err := s.sendEmailToken(user.PendingEmail, user.UserName, token, expiresAt); err != nil {
+			return nil, err
+		}
 	}
+
 	user.Password = ""
+	return user, nil
+}
+
+// UploadProfilePicture validates the uploaded image, stores a resized avatar
+// and a thumbnail, and points the user's avatar URL at the former. The files
+// of the replaced avatar are deleted once the new URL is saved; if saving
+// fails, the new files are deleted instead.
+func (s *UserServiceImpl) UploadProfilePicture(ctx context.Context, userID int, file io.Reader) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.UploadProfilePicture")
+	defer tracing.End(span, &err)
+
+	img, err := readAvatar(file)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := randomToken(8)
+	if err != nil {
+		return nil, err
+	}
+
+	var avatarURL string
+	// The new files are only kept once the account points at them.
+	defer func() {
+		if err != nil {
+			s.deleteAvatar(ctx, user.ID, avatarURL)
+		}
+	}()
+	for _, size := range []int{models.AvatarSize, models.AvatarThumbnailSize} {
+		encoded, err := encodePNG(thumbnail(img, size))
+		if err != nil {
+			return nil, err
+		}
+
+		blobURL, err := s.blobStore.Put(avatarKey(user.ID, version, size), "image/png", encoded)
+		if err != nil {
+			return nil, err
+		}
+		if size == models.AvatarSize {
+			avatarURL = blobURL
+		}
+	}
 
+	previous := user.AvatarURL
+	user.AvatarURL = avatarURL
+	if err := s.userRepo.UpdateUser(ctx, user, "avatar_url"); err != nil {
+		return nil, err
+	}
+	s.deleteAvatar(ctx, user.ID, previous)
+
+	user.Password = ""
 	return user, nil
+}
+
+// DeleteAccount soft-deletes the account. It can be restored by logging in
+// until the grace period has passed and PurgeDeletedAccounts removes it.
+func (s *UserServiceImpl) DeleteAccount(ctx context.Context, userID int) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteAccount")
+	defer tracing.End(span, &err)
+
+	if _, err := s.userRepo.FindUserByID(ctx, userID); err != nil {
+		return models.ErrUserDoesNotExist
+	}
+	if err := s.userRepo.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+	logger.FromContext(ctx).WithFields(logger.Fields{"user_id": userID}).Info("Account scheduled for deletion")
+	return nil
+}
+
+// PurgeDeletedAccounts removes or anonymizes accounts whose grace period has
+// passed, depending on the configured purge mode, and deletes their avatars.
+func (s *UserServiceImpl) PurgeDeletedAccounts(ctx context.Context) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.PurgeDeletedAccounts")
+	defer tracing.End(span, &err)
+
+	users, err := s.userRepo.PurgeDeletedUsers(ctx, time.Now().Add(-s.gracePeriod), s.purgeMode == models.PurgeModeAnonymize)
+	if err != nil {
+		return 0, err
+	}
+	for _, user := range users {
+		s.deleteAvatar(ctx, user.ID, user.AvatarURL)
+	}
+	return int64(len(users)), nil
+}
+
+// PurgeExpiredRegistrations removes pending registrations that were never
+// verified before their token expired.
+func (s *UserServiceImpl) PurgeExpiredRegistrations(ctx context.Context) (_ int64, err error) {
+	_, span := tracing.Start(ctx, "UserService.PurgeExpiredRegistrations")
+	defer tracing.End(span, &err)
+
+	return s.pendingRepo.DeleteExpiredPendingUsers(time.Now())
+}
+
+// deleteAvatar removes the stored files behind avatarURL. The account no
+// longer points at them, so a failure is logged and left behind.
+func (s *UserServiceImpl) deleteAvatar(ctx context.Context, userID int, avatarURL string) {
+	for _, key := range avatarKeys(userID, avatarURL) {
+		if err := s.blobStore.Delete(key); err != nil {
+			logger.FromContext(ctx).WithFields(logger.Fields{"user_id": userID, "key": key}).Error("Failed to delete avatar", err.Error())
+		}
+	}
+}
+
+func (s *UserServiceImpl) restorable(user *models.User) bool {
+	return user.DeletedAt.Valid && time.Since(user.DeletedAt.Time) < s.gracePeriod
+}
+
+func (s *UserServiceImpl) confirmEmailChange(ctx context.Context, email, token string) error {
+	user, err := s.userRepo.FindUserByPendingEmail(ctx, email)
+	if err != nil || user.PendingEmailTokenHash != hashToken(token) {
+		return models.ErrInvalidVerificationToken
+	}
+
+	if exists, _ := s.userRepo.FindUserByEmail(ctx, email); exists != nil {
+		return models.ErrEmailAlreadyInUse
+	}
+
+	user.Email = user.PendingEmail
+	user.PendingEmail = ""
+	user.PendingEmailTokenHash = ""
+	return s.userRepo.UpdateUser(ctx, user, "email", "pending_email", "pending_email_token_hash")
+}
+
+func (s *UserServiceImpl) ensureEmailAvailable(ctx context.Context, email string) error {
+	if exists, _ := s.userRepo.FindUserByEmail(ctx, email); exists != nil {
+		return models.ErrEmailAlreadyInUse
+	}
+	if pending, _ := s.pendingRepo.FindPendingUserByEmail(email); pending != nil {
+		return models.ErrEmailAlreadyInUse
+	}
+	return nil
+}
+
+// concealSignupConflict answers a signup for a registered email the way a
+// successful signup is answered, hashing the password to match its timing,
+// and tells the owner of the address what happened.
+func (s *UserServiceImpl) concealSignupConflict(ctx context.Context, user *models.SignupInput) error {
+	hashPassword(ctx, user.Password)
+
+	body := fmt.Sprintf("Hi,\n\nSomeone tried to sign up with %s, but this address already has an account.\n\n", user.Email)
+	body += "If it was you, log in instead, or reset your password if you forgot it. Otherwise you can ignore this email.\n"
+	if err := s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is already registered",
+		Body:    body,
+	}); err != nil {
+		return err
+	}
+	logger.FromContext(ctx).Info("Concealed signup for a registered email")
+	return nil
+}
+
+func (s *UserServiceImpl) sendVerification(pending *models.TempUser) error {
+	expiresAt := time.Now().Add(s.verification.TTL)
+	token := signEmailToken(s.verification.Secret, pending.Email, expiresAt)
+
+	pending.TokenHash = hashToken(token)
+	pending.ExpiresAt = expiresAt
+	if err := s.pendingRepo.SavePendingUser(pending); err != nil {
+		return err
+	}
 
+	return s.sendEmailToken(pending.Email, pending.UserName, token, expiresAt)
 }
 
-func (s *UserServiceImpl) GetProfile(userID int) (*models.User, error) {
-	return s.userRepo.FindUserByID(userID)
+func (s *UserServiceImpl) sendEmailToken(email, name, token string, expiresAt time.Time) error {
+	body := fmt.Sprintf("Hi %s,\n\nYour email verification code is:\n\n%s\n\n", name, token)
+	if s.verification.LinkURL != "" {
+		body += fmt.Sprintf("You can also confirm by opening this link:\n\n%s?token=%s\n\n", s.verification.LinkURL, url.QueryEscape(token))
+	}
+	body += fmt.Sprintf("The code expires at %s.\n", expiresAt.Format(time.RFC1123))
+
+	return s.mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body:    body,
+	})
 }
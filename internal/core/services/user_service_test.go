@@ -1,21 +1,39 @@
 package services_test
 
 import (
+	"bytes"
 	"clean-arch/internal/core/models"
 	"clean-arch/internal/core/services"
+	"clean-arch/internal/mailer"
 	"clean-arch/internal/mocks"
+	"clean-arch/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) FindUserByEmail(email string) (*models.User, error) {
+func (m *MockUserRepository) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(email)
 	if user, ok := args.Get(0).(*models.User); ok {
 		return user, args.Error(1)
@@ -24,12 +42,12 @@ func (m *MockUserRepository) FindUserByEmail(email string) (*models.User, error)
 	return nil, args.Error(1)
 }
 
-func (m *MockUserRepository) CreateUser(user *models.User) error {
+func (m *MockUserRepository) CreateUser(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) FindUserByID(userID int) (*models.User, error) {
+func (m *MockUserRepository) FindUserByID(ctx context.Context, userID int) (*models.User, error) {
 	args := m.Called(userID)
 	if user, ok := args.Get(0).(*models.User); ok {
 		return user, args.Error(1)
@@ -37,9 +55,81 @@ func (m *MockUserRepository) FindUserByID(userID int) (*models.User, error) {
 	return nil, args.Error(1)
 }
 
+func (m *MockUserRepository) FindUserByPendingEmail(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(email)
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int64, error) {
+	args := m.Called(filter)
+	if users, ok := args.Get(0).([]models.User); ok {
+		return users, args.Get(1).(int64), args.Error(2)
+	}
+	return nil, 0, args.Error(2)
+}
+
+func (m *MockUserRepository) UpdateUser(ctx context.Context, user *models.User, columns ...string) error {
+	args := m.Called(user, columns)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) DeleteUser(ctx context.Context, userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) FindDeletedUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(email)
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserRepository) FindDeletedUserByID(ctx context.Context, userID int) (*models.User, error) {
+	args := m.Called(userID)
+	if user, ok := args.Get(0).(*models.User); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserRepository) RestoreUser(ctx context.Context, userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, anonymize bool) ([]models.User, error) {
+	args := m.Called(deletedBefore, anonymize)
+	if users, ok := args.Get(0).([]models.User); ok {
+		return users, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+var testConfig = services.UserServiceConfig{
+	Verification:        services.VerificationConfig{Secret: []byte("test-secret"), TTL: time.Hour},
+	DeletionGracePeriod: 24 * time.Hour,
+}
+
+// verificationToken pulls the token out of a verification email body.
+func verificationToken(body string) string {
+	for _, field := range strings.Fields(body) {
+		if strings.Count(field, ".") == 2 && !strings.Contains(field, ":") {
+			return field
+		}
+	}
+	return ""
+}
+
 func TestSingup(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	UserService := services.NewUserService(mockRepo)
+	mockPendingRepo := new(mocks.MockPendingUserRepository)
+	mockMailer := mailer.NewMemoryMailer()
+	UserService := services.NewUserService(mockRepo, mockPendingRepo, mockMailer, nil, testConfig)
 
 	input := &models.SignupInput{
 		UserName:    "JohnDoe",
@@ -49,29 +139,35 @@ func TestSingup(t *testing.T) {
 	}
 
 	mockRepo.On("FindUserByEmail", input.Email).Return(nil, nil)
-	mockRepo.On("CreateUser", mock.AnythingOfType("*models.User")).Return(nil)
+	mockRepo.On("FindDeletedUserByEmail", input.Email).Return(nil, errors.New("user not found"))
+	mockPendingRepo.On("SavePendingUser", mock.MatchedBy(func(pending *models.TempUser) bool {
+		return pending.Email == input.Email && pending.Password != input.Password && pending.TokenHash != ""
+	})).Return(nil)
 
-	err := UserService.SignUp(input)
+	err := UserService.SignUp(context.Background(), input)
 
 	assert.NoError(t, err)
+	assert.Len(t, mockMailer.Messages(), 1)
+	assert.Equal(t, input.Email, mockMailer.Messages()[0].To)
 	mockRepo.AssertExpectations(t)
+	mockPendingRepo.AssertExpectations(t)
 
 }
 
 func TestSingupUserExists(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userService := services.NewUserService(mockRepo)
+	userService := services.NewUserService(mockRepo, new(mocks.MockPendingUserRepository), mailer.NewMemoryMailer(), nil, testConfig)
 
 	input := &models.SignupInput{
 		UserName:    "JohnDoe",
-		Email:       "johndoegmail.com",
+		Email:       "johndoe@gmail.com",
 		Password:    "johndoe123",
 		PhoneNumber: "1234567890",
 	}
 
 	mockRepo.On("FindUserByEmail", input.Email).Return(&models.User{}, nil)
 
-	err := userService.SignUp(input)
+	err := userService.SignUp(context.Background(), input)
 
 	assert.EqualError(t, err, models.ErrUserAlreadyExists.Error())
 	mockRepo.AssertExpectations(t)
@@ -80,7 +176,7 @@ func TestSingupUserExists(t *testing.T) {
 func TestGetProfile_Success(t *testing.T) {
 	mockRepo := new(mocks.MockUserRepository)
 
-	service := services.NewUserService(mockRepo)
+	service := services.NewUserService(mockRepo, new(mocks.MockPendingUserRepository), mailer.NewMemoryMailer(), nil, testConfig)
 
 	mockUser := &models.User{
 		ID:          1,
@@ -94,7 +190,7 @@ func TestGetProfile_Success(t *testing.T) {
 
 	mockRepo.On("FindUserByID", 1).Return(mockUser, nil)
 
-	result, err := service.GetProfile(1)
+	result, err := service.GetProfile(context.Background(), 1)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
@@ -109,3 +205,461 @@ func TestGetProfile_Success(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestVerifyEmail_PromotesPendingUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockPendingRepo := new(mocks.MockPendingUserRepository)
+	mockMailer := mailer.NewMemoryMailer()
+	userService := services.NewUserService(mockRepo, mockPendingRepo, mockMailer, nil, testConfig)
+
+	input := &models.SignupInput{
+		UserName:    "JohnDoe",
+		Email:       "johndoe@gmail.com",
+		Password:    "johndoe123",
+		PhoneNumber: "1234567890",
+	}
+
+	var pending *models.TempUser
+	mockRepo.On("FindUserByEmail", input.Email).Return(nil, errors.New("user not found"))
+	mockRepo.On("FindDeletedUserByEmail", input.Email).Return(nil, errors.New("user not found"))
+	mockPendingRepo.On("SavePendingUser", mock.AnythingOfType("*models.TempUser")).
+		Run(func(args mock.Arguments) { pending = args.Get(0).(*models.TempUser) }).
+		Return(nil)
+
+	assert.NoError(t, userService.SignUp(context.Background(), input))
+
+	token := verificationToken(mockMailer.Messages()[0].Body)
+	assert.NotEmpty(t, token)
+
+	mockPendingRepo.On("FindPendingUserByEmail", input.Email).Return(pending, nil)
+	mockPendingRepo.On("PromotePendingUser", pending).Return(&models.User{ID: 1, Email: input.Email}, nil)
+
+	assert.ErrorIs(t, userService.VerifyEmail(context.Background(), token+"x"), models.ErrInvalidVerificationToken)
+	assert.NoError(t, userService.VerifyEmail(context.Background(), token))
+	mockPendingRepo.AssertExpectations(t)
+}
+
+func TestLogin_UnverifiedEmail(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockPendingRepo := new(mocks.MockPendingUserRepository)
+	userService := services.NewUserService(mockRepo, mockPendingRepo, mailer.NewMemoryMailer(), nil, testConfig)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("johndoe123"), bcrypt.MinCost)
+	mockRepo.On("FindUserByEmail", "johndoe@gmail.com").Return(nil, errors.New("user not found"))
+	mockPendingRepo.On("FindPendingUserByEmail", "johndoe@gmail.com").Return(&models.TempUser{Email: "johndoe@gmail.com", Password: string(hash)}, nil)
+
+	user, err := userService.Login(context.Background(), "johndoe@gmail.com", "johndoe123")
+
+	assert.Nil(t, user)
+	assert.ErrorIs(t, err, models.ErrEmailNotVerified)
+
+	// Without the right password the registration is not revealed.
+	_, err = userService.Login(context.Background(), "johndoe@gmail.com", "wrongpassword")
+	assert.ErrorIs(t, err, models.ErrInvalidCredentials)
+	mockRepo.AssertExpectations(t)
+	mockPendingRepo.AssertExpectations(t)
+}
+
+func TestUpdateProfile_EmailChangeRequiresVerification(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockPendingRepo := new(mocks.MockPendingUserRepository)
+	mockMailer := mailer.NewMemoryMailer()
+	userService := services.NewUserService(mockRepo, mockPendingRepo, mockMailer, nil, testConfig)
+
+	newName := "Johnny"
+	newEmail := "johnny@gmail.com"
+	user := &models.User{ID: 1, UserName: "JohnDoe", Email: "johndoe@gmail.com", Password: "hash"}
+
+	mockRepo.On("FindUserByID", 1).Return(user, nil)
+	mockRepo.On("FindUserByEmail", newEmail).Return(nil, errors.New("user not found"))
+	mockPendingRepo.On("FindPendingUserByEmail", newEmail).Return(nil, errors.New("pending user not found"))
+	mockRepo.On("UpdateUser", mock.MatchedBy(func(u *models.User) bool {
+		return u.UserName == newName && u.Email == "johndoe@gmail.com" && u.PendingEmail == newEmail
+	}), []string{"user_name", "phone_number", "pending_email", "pending_email_token_hash"}).Return(nil)
+
+	updated, err := userService.UpdateProfile(context.Background(), 1, &models.UpdateProfileInput{UserName: &newName, Email: &newEmail})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "johndoe@gmail.com", updated.Email)
+	assert.Equal(t, newEmail, updated.PendingEmail)
+	assert.Len(t, mockMailer.Messages(), 1)
+	assert.Equal(t, newEmail, mockMailer.Messages()[0].To)
+
+	token := verificationToken(mockMailer.Messages()[0].Body)
+	mockRepo.On("FindUserByPendingEmail", newEmail).Return(user, nil)
+	mockRepo.On("UpdateUser", mock.MatchedBy(func(u *models.User) bool {
+		return u.Email == newEmail && u.PendingEmail == ""
+	}), []string{"email", "pending_email", "pending_email_token_hash"}).Return(nil)
+
+	assert.NoError(t, userService.VerifyEmail(context.Background(), token))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUploadProfilePicture(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	root := t.TempDir()
+	userService := services.NewUserService(mockRepo, new(mocks.MockPendingUserRepository), mailer.NewMemoryMailer(), storage.NewLocalBlobStore(root, "/uploads"), testConfig)
+
+	img := image.NewRGBA(image.Rect(0, 0, 600, 400))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+	img.Set(0, 0, color.Black)
+	var upload bytes.Buffer
+	assert.NoError(t, png.Encode(&upload, img))
+
+	mockRepo.On("FindUserByID", 1).Return(&models.User{ID: 1}, nil)
+	mockRepo.On("UpdateUser", mock.AnythingOfType("*models.User"), mock.Anything).Return(nil)
+
+	user, err := userService.UploadProfilePicture(context.Background(), 1, &upload)
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(user.AvatarURL, "/uploads/avatars/1/"))
+
+	stored, err := os.Open(filepath.Join(root, strings.TrimPrefix(user.AvatarURL, "/uploads/")))
+	assert.NoError(t, err)
+	defer stored.Close()
+	config, err := png.DecodeConfig(stored)
+	assert.NoError(t, err)
+	assert.Equal(t, models.AvatarSize, config.Width)
+	assert.Equal(t, models.AvatarSize, config.Height)
+
+	thumbnails, _ := filepath.Glob(filepath.Join(root, "avatars", "1", "*-64.png"))
+	assert.Len(t, thumbnails, 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUploadProfilePicture_DeletesReplacedAvatar(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	root := t.TempDir()
+	userService := services.NewUserService(mockRepo, new(mocks.MockPendingUserRepository), mailer.NewMemoryMailer(), storage.NewLocalBlobStore(root, "/uploads"), testConfig)
+
+	var upload bytes.Buffer
+	assert.NoError(t, png.Encode(&upload, image.NewRGBA(image.Rect(0, 0, 300, 300))))
+	picture := upload.Bytes()
+
+	stored := &models.User{ID: 1}
+	mockRepo.On("FindUserByID", 1).Return(stored, nil)
+	mockRepo.On("UpdateUser", mock.AnythingOfType("*models.User"), mock.Anything).Return(nil)
+
+	first, err := userService.UploadProfilePicture(context.Background(), 1, bytes.NewReader(picture))
+	assert.NoError(t, err)
+	firstURL := first.AvatarURL
+
+	second, err := userService.UploadProfilePicture(context.Background(), 1, bytes.NewReader(picture))
+	assert.NoError(t, err)
+	assert.NotEqual(t, firstURL, second.AvatarURL)
+
+	files, _ := filepath.Glob(filepath.Join(root, "avatars", "1", "*.png"))
+	assert.Len(t, files, 2)
+	_, err = os.Stat(filepath.Join(root, strings.TrimPrefix(firstURL, "/uploads/")))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUploadProfilePicture_KeepsOldAvatarWhenSaveFails(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	root := t.TempDir()
+	blobStore := storage.NewLocalBlobStore(root, "/uploads")
+	userService := services.NewUserService(mockRepo, new(mocks.MockPendingUserRepository), mailer.NewMemoryMailer(), blobStore, testConfig)
+
+	oldURL, err := blobStore.Put(fmt.Sprintf("avatars/1/old-%d.png", models.AvatarSize), "image/png", strings.NewReader("old"))
+	assert.NoError(t, err)
+
+	var upload bytes.Buffer
+	assert.NoError(t, png.Encode(&upload, image.NewRGBA(image.Rect(0, 0, 300, 300))))
+
+	mockRepo.On("FindUserByID", 1).Return(&models.User{ID: 1, AvatarURL: oldURL}, nil)
+	mockRepo.On("UpdateUser", mock.AnythingOfType("*models.User"), []string{"avatar_url"}).Return(errors.New("db down"))
+
+	_, err = userService.UploadProfilePicture(context.Background(), 1, &upload)
+
+	assert.Error(t, err)
+	files, _ := filepath.Glob(filepath.Join(root, "avatars", "1", "*.png"))
+	assert.Equal(t, []string{filepath.Join(root, strings.TrimPrefix(oldURL, "/uploads/"))}, files)
+}
+
+func TestUploadProfilePicture_RejectsNonImage(t *testing.T) {
+	userService := services.NewUserService(new(MockUserRepository), new(mocks.MockPendingUserRepository), mailer.NewMemoryMailer(), storage.NewLocalBlobStore(t.TempDir(), "/uploads"), testConfig)
+
+	_, err := userService.UploadProfilePicture(context.Background(), 1, strings.NewReader("<html>not a picture</html>"))
+
+	assert.ErrorIs(t, err, models.ErrUnsupportedImage)
+}
+
+func TestLogin_RestoresAccountWithinGracePeriod(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockPendingRepo := new(mocks.MockPendingUserRepository)
+	userService := services.NewUserService(mockRepo, mockPendingRepo, mailer.NewMemoryMailer(), nil, testConfig)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("johndoe123"), bcrypt.MinCost)
+	deleted := &models.User{
+		ID:        1,
+		Email:     "johndoe@gmail.com",
+		Password:  string(hash),
+		DeletedAt: gorm.DeletedAt{Time: time.Now().Add(-time.Hour), Valid: true},
+	}
+
+	mockRepo.On("FindUserByEmail", deleted.Email).Return(nil, errors.New("user not found"))
+	mockPendingRepo.On("FindPendingUserByEmail", deleted.Email).Return(nil, errors.New("pending user not found"))
+	mockRepo.On("FindDeletedUserByEmail", deleted.Email).Return(deleted, nil)
+	mockRepo.On("RestoreUser", 1).Return(nil)
+
+	user, err := userService.Login(context.Background(), deleted.Email, "johndoe123")
+
+	assert.NoError(t, err)
+	assert.False(t, user.DeletedAt.Valid)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLogin_LeavesRestoreToMFA(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockPendingRepo := new(mocks.MockPendingUserRepository)
+	userService := services.NewUserService(mockRepo, mockPendingRepo, mailer.NewMemoryMailer(), nil, testConfig)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("johndoe123"), bcrypt.MinCost)
+	deleted := &models.User{
+		ID:         1,
+		Email:      "johndoe@gmail.com",
+		Password:   string(hash),
+		MFAEnabled: true,
+		DeletedAt:  gorm.DeletedAt{Time: time.Now().Add(-time.Hour), Valid: true},
+	}
+
+	mockRepo.On("FindUserByEmail", deleted.Email).Return(nil, errors.New("user not found"))
+	mockPendingRepo.On("FindPendingUserByEmail", deleted.Email).Return(nil, errors.New("pending user not found"))
+	mockRepo.On("FindDeletedUserByEmail", deleted.Email).Return(deleted, nil)
+
+	user, err := userService.Login(context.Background(), deleted.Email, "johndoe123")
+
+	assert.NoError(t, err)
+	assert.True(t, user.DeletedAt.Valid)
+	mockRepo.AssertNotCalled(t, "RestoreUser", mock.Anything)
+}
+
+func TestLogin_DeletedAccountPastGracePeriod(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockPendingRepo := new(mocks.MockPendingUserRepository)
+	userService := services.NewUserService(mockRepo, mockPendingRepo, mailer.NewMemoryMailer(), nil, testConfig)
+
+	deleted := &models.User{
+		ID:        1,
+		Email:     "johndoe@gmail.com",
+		DeletedAt: gorm.DeletedAt{Time: time.Now().Add(-48 * time.Hour), Valid: true},
+	}
+
+	mockRepo.On("FindUserByEmail", deleted.Email).Return(nil, errors.New("user not found"))
+	mockPendingRepo.On("FindPendingUserByEmail", deleted.Email).Return(nil, errors.New("pending user not found"))
+	mockRepo.On("FindDeletedUserByEmail", deleted.Email).Return(deleted, nil)
+
+	user, err := userService.Login(context.Background(), deleted.Email, "johndoe123")
+
+	assert.Nil(t, user)
+	assert.ErrorIs(t, err, models.ErrInvalidCredentials)
+	mockRepo.AssertNotCalled(t, "RestoreUser", mock.Anything)
+}
+
+func TestPurgeDeletedAccounts_UsesGracePeriod(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	config := testConfig
+	config.PurgeMode = models.PurgeModeAnonymize
+	userService := services.NewUserService(mockRepo, new(mocks.MockPendingUserRepository), mailer.NewMemoryMailer(), nil, config)
+
+	mockRepo.On("PurgeDeletedUsers", mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= 24*time.Hour && time.Since(before) < 25*time.Hour
+	}), true).Return([]models.User{{ID: 1}, {ID: 2}}, nil)
+
+	purged, err := userService.PurgeDeletedAccounts(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPurgeDeletedAccounts_DeletesAvatars(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	root := t.TempDir()
+	blobStore := storage.NewLocalBlobStore(root, "/uploads")
+	userService := services.NewUserService(mockRepo, new(mocks.MockPendingUserRepository), mailer.NewMemoryMailer(), blobStore, testConfig)
+
+	avatarURL, err := blobStore.Put(fmt.Sprintf("avatars/1/abc-%d.png", models.AvatarSize), "image/png", bytes.NewBufferString("avatar"))
+	assert.NoError(t, err)
+	_, err = blobStore.Put(fmt.Sprintf("avatars/1/abc-%d.png", models.AvatarThumbnailSize), "image/png", bytes.NewBufferString("thumbnail"))
+	assert.NoError(t, err)
+
+	mockRepo.On("PurgeDeletedUsers", mock.AnythingOfType("time.Time"), false).Return([]models.User{{ID: 1, AvatarURL: avatarURL}}, nil)
+
+	purged, err := userService.PurgeDeletedAccounts(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	remaining, _ := filepath.Glob(filepath.Join(root, "avatars", "1", "*"))
+	assert.Empty(t, remaining)
+}
+
+func TestLogin_RecordsServiceAndBcryptSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer provider.Shutdown(context.Background())
+
+	mockRepo := new(MockUserRepository)
+	mockPendingRepo := new(mocks.MockPendingUserRepository)
+	userService := services.NewUserService(mockRepo, mockPendingRepo, mailer.NewMemoryMailer(), nil, testConfig)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("johndoe123"), bcrypt.MinCost)
+	mockRepo.On("FindUserByEmail", "johndoe@gmail.com").Return(&models.User{ID: 1, Email: "johndoe@gmail.com", Password: string(hash)}, nil)
+
+	_, err := userService.Login(context.Background(), "johndoe@gmail.com", "wrongpassword")
+	assert.Error(t, err)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	compare, login := spans[0], spans[1]
+	assert.Equal(t, "bcrypt.compare", compare.Name())
+	assert.Equal(t, "UserService.Login", login.Name())
+	assert.Equal(t, login.SpanContext().SpanID(), compare.Parent().SpanID())
+	assert.Equal(t, codes.Error, login.Status().Code)
+}
+
+func TestLogin_UnknownEmailComparesDummyHash(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer provider.Shutdown(context.Background())
+
+	mockRepo := new(MockUserRepository)
+	mockPendingRepo := new(mocks.MockPendingUserRepository)
+	userService := services.NewUserService(mockRepo, mockPendingRepo, mailer.NewMemoryMailer(), nil, testConfig)
+
+	mockRepo.On("FindUserByEmail", "nobody@gmail.com").Return(nil, errors.New("user not found"))
+	mockPendingRepo.On("FindPendingUserByEmail", "nobody@gmail.com").Return(nil, errors.New("pending user not found"))
+	mockRepo.On("FindDeletedUserByEmail", "nobody@gmail.com").Return(nil, errors.New("user not found"))
+
+	user, err := userService.Login(context.Background(), "nobody@gmail.com", "johndoe123")
+
+	assert.Nil(t, user)
+	assert.Equal(t, models.ErrInvalidCredentials, err)
+
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	assert.Equal(t, []string{"bcrypt.compare", "UserService.Login"}, names)
+}
+
+func TestLogin_BlockedAccountNeedsRightPassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userService := services.NewUserService(mockRepo, new(mocks.MockPendingUserRepository), mailer.NewMemoryMailer(), nil, testConfig)
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("johndoe123"), bcrypt.MinCost)
+	mockRepo.On("FindUserByEmail", "johndoe@gmail.com").Return(&models.User{ID: 1, Email: "johndoe@gmail.com", Password: string(hash), Status: models.StatusBlocked}, nil)
+
+	_, err := userService.Login(context.Background(), "johndoe@gmail.com", "wrongpassword")
+	assert.Equal(t, models.ErrInvalidCredentials, err)
+
+	_, err = userService.Login(context.Background(), "johndoe@gmail.com", "johndoe123")
+	assert.ErrorIs(t, err, models.ErrUserBlocked)
+}
+
+func TestSignUp_ConcealsConflict(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockPendingRepo := new(mocks.MockPendingUserRepository)
+	mockMailer := mailer.NewMemoryMailer()
+	config := testConfig
+	config.ConcealSignupConflicts = true
+	userService := services.NewUserService(mockRepo, mockPendingRepo, mockMailer, nil, config)
+
+	input := &models.SignupInput{
+		UserName:    "JohnDoe",
+		Email:       "johndoe@gmail.com",
+		Password:    "johndoe123",
+		PhoneNumber: "1234567890",
+	}
+	mockRepo.On("FindUserByEmail", input.Email).Return(&models.User{ID: 1, Email: input.Email}, nil)
+
+	err := userService.SignUp(context.Background(), input)
+
+	assert.NoError(t, err)
+	messages := mockMailer.Messages()
+	assert.Len(t, messages, 1)
+	assert.Equal(t, input.Email, messages[0].To)
+	assert.Equal(t, "Your email address is already registered", messages[0].Subject)
+	assert.Empty(t, verificationToken(messages[0].Body))
+	mockPendingRepo.AssertNotCalled(t, "SavePendingUser", mock.Anything)
+}
+
+func TestSignUp_ValidatesBeforeConcealing(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockPendingRepo := new(mocks.MockPendingUserRepository)
+	mockMailer := mailer.NewMemoryMailer()
+	config := testConfig
+	config.ConcealSignupConflicts = true
+	userService := services.NewUserService(mockRepo, mockPendingRepo, mockMailer, nil, config)
+
+	mockRepo.On("FindUserByEmail", "johndoe@gmail.com").Return(&models.User{ID: 1, Email: "johndoe@gmail.com"}, nil)
+	mockRepo.On("FindUserByEmail", "nobody@gmail.com").Return(nil, models.ErrUserDoesNotExist)
+	mockRepo.On("FindDeletedUserByEmail", "nobody@gmail.com").Return(nil, models.ErrUserDoesNotExist)
+
+	var errs []error
+	for _, email := range []string{"johndoe@gmail.com", "nobody@gmail.com"} {
+		errs = append(errs, userService.SignUp(context.Background(), &models.SignupInput{
+			UserName:    "JohnDoe",
+			Email:       email,
+			Password:    "short",
+			PhoneNumber: "1234567890",
+		}))
+	}
+
+	assert.Equal(t, models.CodeValidation, models.ErrorCodeOf(errs[0]))
+	assert.Equal(t, errs[1].Error(), errs[0].Error())
+	assert.Empty(t, mockMailer.Messages())
+	mockRepo.AssertNotCalled(t, "FindUserByEmail", mock.Anything)
+	mockPendingRepo.AssertNotCalled(t, "SavePendingUser", mock.Anything)
+}
+
+func TestSignUp_FailsWhenHashingFails(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockPendingRepo := new(mocks.MockPendingUserRepository)
+	userService := services.NewUserService(mockRepo, mockPendingRepo, mailer.NewMemoryMailer(), nil, testConfig)
+
+	cost := services.BcryptCost
+	services.BcryptCost = bcrypt.MaxCost + 1
+	t.Cleanup(func() { services.BcryptCost = cost })
+
+	mockRepo.On("FindUserByEmail", "nobody@gmail.com").Return(nil, models.ErrUserDoesNotExist)
+	mockRepo.On("FindDeletedUserByEmail", "nobody@gmail.com").Return(nil, models.ErrUserDoesNotExist)
+
+	err := userService.SignUp(context.Background(), &models.SignupInput{
+		UserName:    "JohnDoe",
+		Email:       "nobody@gmail.com",
+		Password:    "a password that is long",
+		PhoneNumber: "1234567890",
+	})
+
+	assert.Error(t, err)
+	mockPendingRepo.AssertNotCalled(t, "SavePendingUser", mock.Anything)
+}
+
+func TestSignUp_ConcealsConflictWithRestorableAccount(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockMailer := mailer.NewMemoryMailer()
+	config := testConfig
+	config.ConcealSignupConflicts = true
+	userService := services.NewUserService(mockRepo, new(mocks.MockPendingUserRepository), mockMailer, nil, config)
+
+	input := &models.SignupInput{
+		UserName:    "JohnDoe",
+		Email:       "johndoe@gmail.com",
+		Password:    "johndoe123",
+		PhoneNumber: "1234567890",
+	}
+	mockRepo.On("FindUserByEmail", input.Email).Return(nil, errors.New("user not found"))
+	mockRepo.On("FindDeletedUserByEmail", input.Email).Return(&models.User{
+		ID:        1,
+		Email:     input.Email,
+		DeletedAt: gorm.DeletedAt{Time: time.Now().Add(-time.Hour), Valid: true},
+	}, nil)
+
+	assert.NoError(t, userService.SignUp(context.Background(), input))
+	assert.Len(t, mockMailer.Messages(), 1)
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type VerificationConfig struct {
+	// Secret signs verification tokens.
+	Secret []byte
+	// TTL is how long a verification token stays valid.
+	TTL time.Duration
+	// LinkURL is the page users land on from the email; the token is
+	// appended as a "token" query parameter.
+	LinkURL string
+}
+
+// signEmailToken produces "<email>.<expiry>.<signature>", each part base64url
+// or decimal encoded, so the token can be checked without a database lookup.
+func signEmailToken(secret []byte, email string, expiresAt time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(email)) + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(emailTokenMAC(secret, payload))
+}
+
+// parseEmailToken returns the email a token was issued for, provided its
+// signature is valid and it has not expired.
+func parseEmailToken(secret []byte, token string, now time.Time) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, emailTokenMAC(secret, parts[0]+"."+parts[1])) {
+		return "", false
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() > expiresAt {
+		return "", false
+	}
+
+	email, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	return string(email), true
+}
+
+func emailTokenMAC(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,130 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusReady        = "ready"
+	StatusNotReady     = "not_ready"
+	StatusShuttingDown = "shutting_down"
+
+	CheckUp   = "up"
+	CheckDown = "down"
+)
+
+// CheckFunc reports whether a dependency is usable. It should give up when
+// ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+
+	// Error is why the check failed. It is left out of the JSON report,
+	// which the unauthenticated readiness probe serves, and only logged.
+	Error string `json:"-"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+func (r Report) Ready() bool {
+	return r.Status == StatusReady
+}
+
+// Health runs the registered dependency checks for the readiness probe.
+type Health struct {
+	timeout      time.Duration
+	mu           sync.RWMutex
+	checks       map[string]CheckFunc
+	shuttingDown atomic.Bool
+}
+
+// New returns a Health whose checks each get timeout to complete.
+func New(timeout time.Duration) *Health {
+	return &Health{
+		timeout: timeout,
+		checks:  make(map[string]CheckFunc),
+	}
+}
+
+func (h *Health) Register(name string, check CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+// ShutDown marks the process as not ready, so traffic is routed elsewhere
+// while in-flight requests drain.
+func (h *Health) ShutDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Check runs every registered check concurrently. The process is ready when
+// all of them pass and it is not shutting down.
+func (h *Health) Check(ctx context.Context) Report {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]CheckFunc, len(names))
+	for i, name := range names {
+		checks[i] = h.checks[name]
+	}
+	h.mu.RUnlock()
+
+	results := make([]CheckResult, len(names))
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = h.run(ctx, checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Checks: make(map[string]CheckResult, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != CheckUp {
+			report.Status = StatusNotReady
+		}
+	}
+	if h.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+func (h *Health) run(ctx context.Context, check CheckFunc) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() { errc <- check(ctx) }()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: CheckUp, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = CheckDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,72 @@
+package health_test
+
+import (
+	"clean-arch/internal/health"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck_AllUp(t *testing.T) {
+	h := health.New(time.Second)
+	h.Register("database", func(context.Context) error { return nil })
+
+	report := h.Check(context.Background())
+
+	assert.True(t, report.Ready())
+	assert.Equal(t, health.CheckUp, report.Checks["database"].Status)
+}
+
+func TestCheck_ReportsFailingDependency(t *testing.T) {
+	h := health.New(time.Second)
+	h.Register("database", func(context.Context) error { return errors.New("connection refused") })
+	h.Register("mailer", func(context.Context) error { return nil })
+
+	report := h.Check(context.Background())
+
+	assert.Equal(t, health.StatusNotReady, report.Status)
+	assert.Equal(t, health.CheckDown, report.Checks["database"].Status)
+	assert.Equal(t, "connection refused", report.Checks["database"].Error)
+	assert.Equal(t, health.CheckUp, report.Checks["mailer"].Status)
+}
+
+func TestReport_OmitsCheckErrors(t *testing.T) {
+	h := health.New(time.Second)
+	h.Register("database", func(context.Context) error { return errors.New("dial tcp 10.0.0.5:5432: connection refused") })
+
+	body, err := json.Marshal(h.Check(context.Background()))
+
+	assert.NoError(t, err)
+	assert.NotContains(t, string(body), "10.0.0.5")
+	assert.Contains(t, string(body), `"database":{"status":"down"`)
+}
+
+func TestCheck_TimesOutSlowChecks(t *testing.T) {
+	h := health.New(10 * time.Millisecond)
+	h.Register("database", func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report := h.Check(context.Background())
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, health.CheckDown, report.Checks["database"].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["database"].Error)
+}
+
+func TestCheck_NotReadyWhileShuttingDown(t *testing.T) {
+	h := health.New(time.Second)
+	h.Register("database", func(context.Context) error { return nil })
+
+	h.ShutDown()
+	report := h.Check(context.Background())
+
+	assert.False(t, report.Ready())
+	assert.Equal(t, health.StatusShuttingDown, report.Status)
+}
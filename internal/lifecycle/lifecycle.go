@@ -0,0 +1,186 @@
+package lifecycle
+
+import (
+	"clean-arch/internal/logger"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Hook is a component with a start and a stop step. Either may be nil.
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Lifecycle starts hooks in the order they were appended and stops them in
+// reverse, so a component is stopped before the ones it depends on.
+type Lifecycle struct {
+	log     logger.Logger
+	hooks   []Hook
+	started int
+	failed  chan error
+}
+
+func New(log logger.Logger) *Lifecycle {
+	return &Lifecycle{
+		log:    log,
+		failed: make(chan error, 1),
+	}
+}
+
+func (l *Lifecycle) Append(hook Hook) {
+	l.hooks = append(l.hooks, hook)
+}
+
+// Fail makes Run shut down, for components that fail after they started.
+func (l *Lifecycle) Fail(err error) {
+	select {
+	case l.failed <- err:
+	default:
+	}
+}
+
+// Run starts every hook, waits until ctx is done or a component fails, then
+// stops the started hooks within stopTimeout. It returns the error that
+// caused the shutdown, if any, together with the errors of the stop steps.
+func (l *Lifecycle) Run(ctx context.Context, stopTimeout time.Duration) error {
+	startErr := l.Start(ctx)
+
+	var cause error
+	if startErr != nil {
+		cause = startErr
+	} else {
+		select {
+		case <-ctx.Done():
+			l.log.Info("Shutting down")
+		case cause = <-l.failed:
+			l.log.Error("Shutting down after a failure", cause.Error())
+		}
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	return errors.Join(cause, l.Stop(stopCtx))
+}
+
+// Start runs the start steps in order and stops at the first error. Hooks
+// that already started are left for Stop.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	for _, hook := range l.hooks[l.started:] {
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				return errors.New("failed to start " + hook.Name + ": " + err.Error())
+			}
+		}
+		l.started++
+	}
+	return nil
+}
+
+// Stop runs the stop steps of the started hooks in reverse order. Every
+// hook is stopped even if an earlier one fails.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	hooks := l.hooks[:l.started]
+	l.started = 0
+	return l.stop(ctx, hooks)
+}
+
+// Abort is the shutdown for a failure before Run. Hooks without a start
+// step hold their resource from the moment they are appended, so they are
+// stopped along with the started ones.
+func (l *Lifecycle) Abort(ctx context.Context) error {
+	var hooks []Hook
+	for i, hook := range l.hooks {
+		if i < l.started || hook.OnStart == nil {
+			hooks = append(hooks, hook)
+		}
+	}
+	l.started = 0
+	return l.stop(ctx, hooks)
+}
+
+func (l *Lifecycle) stop(ctx context.Context, hooks []Hook) error {
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		if hook.OnStop == nil {
+			continue
+		}
+		l.log.Info("Stopping", hook.Name)
+		if err := hook.OnStop(ctx); err != nil {
+			errs = append(errs, errors.New("failed to stop "+hook.Name+": "+err.Error()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AppendServer serves srv on srv.Addr, which is updated to the bound address
+// once listening. Stopping it stops accepting connections and waits for
+// in-flight requests until the stop deadline.
+func (l *Lifecycle) AppendServer(name string, srv *http.Server) {
+	l.Append(Hook{
+		Name: name,
+		OnStart: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+			srv.Addr = listener.Addr().String()
+			l.log.Info("Listening", name, srv.Addr)
+			go func() {
+				if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					l.Fail(errors.New(name + ": " + err.Error()))
+				}
+			}()
+			return nil
+		},
+		OnStop: srv.Shutdown,
+	})
+}
+
+// AppendTicker calls fn every interval in the background. Stopping it lets
+// a running call finish.
+func (l *Lifecycle) AppendTicker(name string, interval time.Duration, fn func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	l.Append(Hook{
+		Name: name,
+		OnStart: func(ctx context.Context) error {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ticker.C:
+						fn()
+					case <-done:
+						return
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(done)
+			stopped := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+}
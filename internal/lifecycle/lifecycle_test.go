@@ -0,0 +1,141 @@
+package lifecycle_test
+
+import (
+	"clean-arch/internal/lifecycle"
+	"clean-arch/internal/logger"
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recordingHook(name string, calls *[]string, startErr error) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			*calls = append(*calls, "start "+name)
+			return startErr
+		},
+		OnStop: func(context.Context) error {
+			*calls = append(*calls, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestRun_StopsInReverseOrder(t *testing.T) {
+	var calls []string
+	app := lifecycle.New(logger.NewLogrusLogger())
+	app.Append(recordingHook("database", &calls, nil))
+	app.Append(recordingHook("worker", &calls, nil))
+	app.Append(recordingHook("server", &calls, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.NoError(t, app.Run(ctx, time.Second))
+	assert.Equal(t, []string{
+		"start database", "start worker", "start server",
+		"stop server", "stop worker", "stop database",
+	}, calls)
+}
+
+func TestRun_StartFailureStopsStartedHooks(t *testing.T) {
+	var calls []string
+	app := lifecycle.New(logger.NewLogrusLogger())
+	app.Append(recordingHook("database", &calls, nil))
+	app.Append(recordingHook("server", &calls, errors.New("address in use")))
+	app.Append(recordingHook("worker", &calls, nil))
+
+	err := app.Run(context.Background(), time.Second)
+
+	assert.ErrorContains(t, err, "failed to start server: address in use")
+	assert.Equal(t, []string{"start database", "start server", "stop database"}, calls)
+}
+
+func TestRun_FailAfterStart(t *testing.T) {
+	app := lifecycle.New(logger.NewLogrusLogger())
+	app.Append(lifecycle.Hook{
+		Name: "worker",
+		OnStart: func(context.Context) error {
+			go app.Fail(errors.New("worker crashed"))
+			return nil
+		},
+	})
+
+	assert.ErrorContains(t, app.Run(context.Background(), time.Second), "worker crashed")
+}
+
+func TestAbort_StopsHooksWithoutStartStep(t *testing.T) {
+	var calls []string
+	app := lifecycle.New(logger.NewLogrusLogger())
+	app.Append(lifecycle.Hook{
+		Name: "database",
+		OnStop: func(context.Context) error {
+			calls = append(calls, "stop database")
+			return errors.New("close failed")
+		},
+	})
+	app.Append(recordingHook("server", &calls, nil))
+	app.Append(lifecycle.Hook{
+		Name: "mail",
+		OnStop: func(context.Context) error {
+			calls = append(calls, "stop mail")
+			return nil
+		},
+	})
+
+	err := app.Abort(context.Background())
+
+	assert.ErrorContains(t, err, "failed to stop database: close failed")
+	assert.Equal(t, []string{"stop mail", "stop database"}, calls)
+}
+
+func TestAppendTicker_StopsWorker(t *testing.T) {
+	var runs atomic.Int32
+	app := lifecycle.New(logger.NewLogrusLogger())
+	app.AppendTicker("purge", time.Millisecond, func() { runs.Add(1) })
+
+	assert.NoError(t, app.Start(context.Background()))
+	assert.Eventually(t, func() bool { return runs.Load() > 0 }, time.Second, time.Millisecond)
+	assert.NoError(t, app.Stop(context.Background()))
+
+	stopped := runs.Load()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, stopped, runs.Load())
+}
+
+func TestAppendServer_DrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	srv := &http.Server{
+		Addr: "127.0.0.1:0",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	}
+
+	app := lifecycle.New(logger.NewLogrusLogger())
+	app.AppendServer("http server", srv)
+	assert.NoError(t, app.Start(context.Background()))
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + srv.Addr)
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+
+	<-started
+	assert.NoError(t, app.Stop(context.Background()))
+	assert.Equal(t, http.StatusNoContent, <-status)
+}
@@ -0,0 +1,48 @@
+package logger
+
+import "context"
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+// NewContext returns a copy of ctx that carries log, for code further down
+// the call chain to pick up with FromContext.
+func NewContext(ctx context.Context, log Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, log)
+}
+
+// FromContext returns the logger stored in ctx, or one that discards
+// everything if there is none.
+func FromContext(ctx context.Context) Logger {
+	if log, ok := ctx.Value(loggerKey).(Logger); ok {
+		return log
+	}
+	return Discard
+}
+
+// WithRequestID returns a copy of ctx that carries the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID carried by ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Discard is a logger that drops every entry.
+var Discard Logger = discard{}
+
+type discard struct{}
+
+func (discard) Info(string, ...interface{})          {}
+func (discard) Error(string, ...interface{})         {}
+func (discard) Debug(string, ...interface{})         {}
+func (discard) Warn(string, ...interface{})          {}
+func (d discard) WithFields(Fields) Logger           { return d }
+func (d discard) WithContext(context.Context) Logger { return d }
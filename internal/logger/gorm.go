@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger sends GORM's logs through a Logger. Queries run with a request
+// context are logged with that request's logger, so they carry its request ID.
+type GormLogger struct {
+	log           Logger
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+// NewGormLogger logs failed queries as errors and queries slower than
+// slowThreshold as warnings. A zero threshold disables slow query logs.
+func NewGormLogger(log Logger, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{log: log, level: gormlogger.Warn, slowThreshold: slowThreshold}
+}
+
+func (g *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *g
+	clone.level = level
+	return &clone
+}
+
+func (g *GormLogger) Info(ctx context.Context, message string, args ...interface{}) {
+	if g.level >= gormlogger.Info {
+		g.from(ctx).Info(fmt.Sprintf(message, args...))
+	}
+}
+
+func (g *GormLogger) Warn(ctx context.Context, message string, args ...interface{}) {
+	if g.level >= gormlogger.Warn {
+		g.from(ctx).Warn(fmt.Sprintf(message, args...))
+	}
+}
+
+func (g *GormLogger) Error(ctx context.Context, message string, args ...interface{}) {
+	if g.level >= gormlogger.Error {
+		g.from(ctx).Error(fmt.Sprintf(message, args...))
+	}
+}
+
+func (g *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if g.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && g.level >= gormlogger.Error:
+		sql, rows := fc()
+		g.from(ctx).WithFields(queryFields(sql, rows, elapsed)).Error("Query failed", err.Error())
+	case g.slowThreshold > 0 && elapsed > g.slowThreshold && g.level >= gormlogger.Warn:
+		sql, rows := fc()
+		g.from(ctx).WithFields(queryFields(sql, rows, elapsed)).Warn("Slow query")
+	case g.level >= gormlogger.Info:
+		sql, rows := fc()
+		g.from(ctx).WithFields(queryFields(sql, rows, elapsed)).Debug("Query")
+	}
+}
+
+func (g *GormLogger) from(ctx context.Context) Logger {
+	if log, ok := ctx.Value(loggerKey).(Logger); ok {
+		return log
+	}
+	return g.log.WithContext(ctx)
+}
+
+func queryFields(sql string, rows int64, elapsed time.Duration) Fields {
+	return Fields{"sql": sql, "rows": rows, "duration_ms": float64(elapsed.Microseconds()) / 1000}
+}
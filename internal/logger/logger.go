@@ -1,37 +1,87 @@
 package logger
 
-import "github.com/sirupsen/logrus"
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Fields are structured key/value pairs attached to every entry of a logger.
+type Fields map[string]interface{}
 
 type Logger interface {
 	Info(message string, args ...interface{})
 	Error(message string, args ...interface{})
 	Debug(message string, args ...interface{})
 	Warn(message string, args ...interface{})
+	// WithFields returns a logger that adds fields to every entry.
+	WithFields(fields Fields) Logger
+	// WithContext returns a logger that adds the request ID and the trace
+	// carried by ctx.
+	WithContext(ctx context.Context) Logger
 }
 
 type LogrusLogger struct {
 	logger *logrus.Logger
+	entry  *logrus.Entry
 }
 
 func NewLogrusLogger() *LogrusLogger {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
 	logger.SetLevel(logrus.InfoLevel)
-	return &LogrusLogger{logger: logger}
+	return &LogrusLogger{logger: logger, entry: logrus.NewEntry(logger)}
 }
 
 func (l *LogrusLogger) Info(message string, args ...interface{}) {
-	l.logger.WithFields(logrus.Fields{"args": args}).Info(message)
+	l.withArgs(args).Info(message)
 }
 
 func (l *LogrusLogger) Error(message string, args ...interface{}) {
-	l.logger.WithFields(logrus.Fields{"args": args}).Error(message)
+	l.withArgs(args).Error(message)
 }
 
 func (l *LogrusLogger) Debug(message string, args ...interface{}) {
-	l.logger.WithFields(logrus.Fields{"args": args}).Debug(message)
+	l.withArgs(args).Debug(message)
 }
 
 func (l *LogrusLogger) Warn(message string, args ...interface{}) {
-	l.logger.WithFields(logrus.Fields{"args": args}).Warn(message)
+	l.withArgs(args).Warn(message)
+}
+
+func (l *LogrusLogger) withArgs(args []interface{}) *logrus.Entry {
+	if len(args) == 0 {
+		return l.entry
+	}
+	return l.entry.WithField("args", args)
+}
+
+func (l *LogrusLogger) WithFields(fields Fields) Logger {
+	return &LogrusLogger{logger: l.logger, entry: l.entry.WithFields(logrus.Fields(fields))}
+}
+
+func (l *LogrusLogger) WithContext(ctx context.Context) Logger {
+	fields := Fields{}
+	if id := RequestID(ctx); id != "" {
+		fields["request_id"] = id
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		fields["trace_id"] = span.TraceID().String()
+		fields["span_id"] = span.SpanID().String()
+	}
+	if len(fields) == 0 {
+		return l
+	}
+	return l.WithFields(fields)
+}
+
+// SetLevel changes the minimum level that is logged, e.g. "debug" or "warn".
+func (l *LogrusLogger) SetLevel(level string) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.logger.SetLevel(parsed)
+	return nil
 }
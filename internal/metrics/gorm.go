@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const gormStartKey = "metrics:start"
+
+// GormPlugin records the duration of every statement run through a
+// *gorm.DB in DBQueryDuration.
+type GormPlugin struct {
+	// Database is the database label, e.g. "primary" or "replica".
+	Database string
+}
+
+func (p *GormPlugin) Name() string {
+	return "metrics:" + p.Database
+}
+
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	name := p.Name()
+
+	// The callback processors are unexported types, so each operation is
+	// registered by hand.
+	for _, err := range []error{
+		callback.Create().Before("gorm:create").Register(name+":before_create", before),
+		callback.Create().After("gorm:create").Register(name+":after_create", p.after("create")),
+		callback.Query().Before("gorm:query").Register(name+":before_query", before),
+		callback.Query().After("gorm:query").Register(name+":after_query", p.after("query")),
+		callback.Update().Before("gorm:update").Register(name+":before_update", before),
+		callback.Update().After("gorm:update").Register(name+":after_update", p.after("update")),
+		callback.Delete().Before("gorm:delete").Register(name+":before_delete", before),
+		callback.Delete().After("gorm:delete").Register(name+":after_delete", p.after("delete")),
+		callback.Row().Before("gorm:row").Register(name+":before_row", before),
+		callback.Row().After("gorm:row").Register(name+":after_row", p.after("row")),
+		callback.Raw().Before("gorm:raw").Register(name+":before_raw", before),
+		callback.Raw().After("gorm:raw").Register(name+":after_raw", p.after("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func before(db *gorm.DB) {
+	db.InstanceSet(gormStartKey, time.Now())
+}
+
+func (p *GormPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(gormStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		DBQueryDuration.WithLabelValues(p.Database, operation, db.Statement.Table).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metric names and label values are relied on by dashboards and alerts.
+// Treat them as a public API: add new ones freely, but rename or remove one
+// only together with everything that queries it.
+const namespace = "user_api"
+
+// Results of a login attempt, the result label of user_api_auth_logins_total.
+const (
+	LoginSuccess     = "success"
+	LoginMFARequired = "mfa_required"
+	LoginFailure     = "failure"
+	LoginBlocked     = "blocked"
+	LoginThrottled   = "throttled"
+	LoginUnverified  = "unverified"
+	LoginInvalid     = "invalid"
+	LoginError       = "error"
+)
+
+// Results of a signup, the result label of user_api_auth_signups_total.
+const (
+	SignupSuccess = "success"
+	SignupExists  = "exists"
+	SignupInvalid = "invalid"
+	SignupError   = "error"
+)
+
+// Reasons for rejecting an access token, the reason label of
+// user_api_auth_token_validation_failures_total.
+const (
+	TokenMissing      = "missing"
+	TokenInvalid      = "invalid"
+	TokenRevoked      = "revoked"
+	TokenWrongPurpose = "wrong_purpose"
+)
+
+var (
+	// HTTPRequests counts handled requests. route is the Gin route pattern,
+	// e.g. /api/v1/admin/users/:id, or "unmatched" for unknown paths.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes request latency in seconds.
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// Logins counts login attempts by result (Login* constants).
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "logins_total",
+		Help:      "Login attempts by result.",
+	}, []string{"result"})
+
+	// Signups counts signups by result (Signup* constants).
+	Signups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "signups_total",
+		Help:      "Signups by result.",
+	}, []string{"result"})
+
+	// TokenValidationFailures counts access tokens rejected by the auth
+	// middleware, by reason (Token* constants).
+	TokenValidationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "token_validation_failures_total",
+		Help:      "Access tokens rejected by the auth middleware, by reason.",
+	}, []string{"reason"})
+
+	// BcryptDuration observes bcrypt in seconds; operation is "hash" or
+	// "compare".
+	BcryptDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "bcrypt_duration_seconds",
+		Help:      "Time spent hashing and comparing passwords.",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	// DBQueryDuration observes GORM statements in seconds. database is
+	// "primary" or "replica", operation is create, query, update, delete,
+	// row or raw, and table is the table name, or "" for raw SQL.
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Database statement latency by database, operation and table.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"database", "operation", "table"})
+)
+
+// Registry holds the application metrics along with the Go runtime and
+// process collectors.
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		Logins,
+		Signups,
+		TokenValidationFailures,
+		BcryptDuration,
+		DBQueryDuration,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format.
+func Handler() gin.HandlerFunc {
+	handler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	return gin.WrapH(handler)
+}
+
+// Middleware records HTTP request counts and latencies.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveBcrypt times a bcrypt operation.
+func ObserveBcrypt(operation string, start time.Time) {
+	BcryptDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,65 @@
+package metrics_test
+
+import (
+	"clean-arch/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	router := gin.New()
+	router.Use(metrics.Middleware())
+	router.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	requests := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/users/:id", "204")
+	unmatched := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "unmatched", "404")
+	before, beforeUnmatched := testutil.ToFloat64(requests), testutil.ToFloat64(unmatched)
+
+	for _, path := range []string{"/users/1", "/users/2", "/nothing-here"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, before+2, testutil.ToFloat64(requests))
+	assert.Equal(t, beforeUnmatched+1, testutil.ToFloat64(unmatched))
+}
+
+func TestHandler_ExposesMetrics(t *testing.T) {
+	metrics.Logins.WithLabelValues(metrics.LoginSuccess).Inc()
+
+	router := gin.New()
+	router.GET("/metrics", metrics.Handler())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `user_api_auth_logins_total{result="success"}`)
+	assert.Contains(t, rec.Body.String(), "go_goroutines")
+}
+
+func TestGormPlugin_ObservesQueries(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer sqlDB.Close()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.Use(&metrics.GormPlugin{Database: "test"}))
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("admin"))
+	series := testutil.CollectAndCount(metrics.DBQueryDuration)
+
+	var names []string
+	assert.NoError(t, db.Table("roles").Pluck("name", &names).Error)
+
+	// The first observation for the test database adds its series.
+	assert.Equal(t, series+1, testutil.CollectAndCount(metrics.DBQueryDuration))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
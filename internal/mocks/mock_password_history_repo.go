@@ -0,0 +1,24 @@
+package mocks
+
+import (
+	"clean-arch/internal/core/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPasswordHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordHistoryRepository) AddPasswordHistory(entry *models.PasswordHistory) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
+
+func (m *MockPasswordHistoryRepository) FindRecentPasswordHashes(userID int, limit int) ([]string, error) {
+	args := m.Called(userID, limit)
+	if args.Get(0) != nil {
+		return args.Get(0).([]string), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"clean-arch/internal/core/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPasswordResetRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetRepository) CreatePasswordReset(reset *models.PasswordResetToken) error {
+	args := m.Called(reset)
+	return args.Error(0)
+}
+
+func (m *MockPasswordResetRepository) FindPasswordResetByHash(hash string) (*models.PasswordResetToken, error) {
+	args := m.Called(hash)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.PasswordResetToken), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPasswordResetRepository) MarkPasswordResetUsed(id int, usedAt time.Time) (bool, error) {
+	args := m.Called(id, usedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPasswordResetRepository) InvalidateUserPasswordResets(userID int, usedAt time.Time) error {
+	args := m.Called(userID, usedAt)
+	return args.Error(0)
+}
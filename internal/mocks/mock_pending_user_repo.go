@@ -0,0 +1,38 @@
+package mocks
+
+import (
+	"clean-arch/internal/core/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPendingUserRepository struct {
+	mock.Mock
+}
+
+func (m *MockPendingUserRepository) SavePendingUser(pending *models.TempUser) error {
+	args := m.Called(pending)
+	return args.Error(0)
+}
+
+func (m *MockPendingUserRepository) FindPendingUserByEmail(email string) (*models.TempUser, error) {
+	args := m.Called(email)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.TempUser), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPendingUserRepository) PromotePendingUser(pending *models.TempUser) (*models.User, error) {
+	args := m.Called(pending)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.User), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPendingUserRepository) DeleteExpiredPendingUsers(now time.Time) (int64, error) {
+	args := m.Called(now)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,27 @@
+package mocks
+
+import (
+	"clean-arch/internal/core/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRecoveryCodeRepository struct {
+	mock.Mock
+}
+
+func (m *MockRecoveryCodeRepository) ReplaceRecoveryCodes(userID int, codes []models.RecoveryCode) error {
+	args := m.Called(userID, codes)
+	return args.Error(0)
+}
+
+func (m *MockRecoveryCodeRepository) UseRecoveryCode(userID int, hash string, usedAt time.Time) (bool, error) {
+	args := m.Called(userID, hash, usedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRecoveryCodeRepository) DeleteRecoveryCodes(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
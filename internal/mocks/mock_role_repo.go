@@ -0,0 +1,86 @@
+package mocks
+
+import (
+	"clean-arch/internal/core/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoleRepository) ListRoles() ([]models.Role, error) {
+	args := m.Called()
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.Role), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRoleRepository) FindRoleByName(name string) (*models.Role, error) {
+	args := m.Called(name)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Role), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRoleRepository) CreateRole(role *models.Role) error {
+	args := m.Called(role)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) DeleteRole(role *models.Role) error {
+	args := m.Called(role)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) SetRolePermissions(role *models.Role, permissions []models.Permission) error {
+	args := m.Called(role, permissions)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) ListPermissions() ([]models.Permission, error) {
+	args := m.Called()
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.Permission), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRoleRepository) SavePermissions(permissions []models.Permission) error {
+	args := m.Called(permissions)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) FindUserRoles(userID int) ([]models.Role, error) {
+	args := m.Called(userID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.Role), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRoleRepository) AssignUserRole(userID int, role *models.Role) error {
+	args := m.Called(userID, role)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) RemoveUserRole(userID int, role *models.Role) error {
+	args := m.Called(userID, role)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) CountRoleUsers(name string) (int64, error) {
+	args := m.Called(name)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRoleRepository) FindRoleUserIDs(role *models.Role) ([]int, error) {
+	args := m.Called(role)
+	if args.Get(0) != nil {
+		return args.Get(0).([]int), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -0,0 +1,45 @@
+package mocks
+
+import (
+	"clean-arch/internal/core/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) FindRefreshTokenByHash(hash string) (*models.RefreshToken, error) {
+	args := m.Called(hash)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.RefreshToken), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) MarkRefreshTokenUsed(id int, usedAt time.Time) (bool, error) {
+	args := m.Called(id, usedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RevokeRefreshTokenFamily(familyID string, revokedAt time.Time) error {
+	args := m.Called(familyID, revokedAt)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeUserRefreshTokens(userID int, revokedAt time.Time) error {
+	args := m.Called(userID, revokedAt)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) DeleteExpiredRefreshTokens(now time.Time) error {
+	args := m.Called(now)
+	return args.Error(0)
+}
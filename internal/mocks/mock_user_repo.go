@@ -2,6 +2,8 @@ package mocks
 
 import (
 	"clean-arch/internal/core/models"
+	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -10,12 +12,12 @@ type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) CreateUser(user *models.User) error {
+func (m *MockUserRepository) CreateUser(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) FindUserByEmail(email string) (*models.User, error) {
+func (m *MockUserRepository) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	args := m.Called(email)
 	if args.Get(0) != nil {
 		return args.Get(0).(*models.User), args.Error(1)
@@ -23,10 +25,65 @@ func (m *MockUserRepository) FindUserByEmail(email string) (*models.User, error)
 	return nil, args.Error(1)
 }
 
-func (m *MockUserRepository) FindUserByID(id int) (*models.User, error) {
+func (m *MockUserRepository) FindUserByID(ctx context.Context, id int) (*models.User, error) {
 	args := m.Called(id)
 	if args.Get(0) != nil {
 		return args.Get(0).(*models.User), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
+
+func (m *MockUserRepository) FindUserByPendingEmail(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(email)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.User), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int64, error) {
+	args := m.Called(filter)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.User), args.Get(1).(int64), args.Error(2)
+	}
+	return nil, 0, args.Error(2)
+}
+
+func (m *MockUserRepository) UpdateUser(ctx context.Context, user *models.User, columns ...string) error {
+	args := m.Called(user, columns)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) DeleteUser(ctx context.Context, userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) FindDeletedUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(email)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.User), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserRepository) FindDeletedUserByID(ctx context.Context, userID int) (*models.User, error) {
+	args := m.Called(userID)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.User), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserRepository) RestoreUser(ctx context.Context, userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, anonymize bool) ([]models.User, error) {
+	args := m.Called(deletedBefore, anonymize)
+	if users, ok := args.Get(0).([]models.User); ok {
+		return users, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
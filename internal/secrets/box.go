@@ -0,0 +1,56 @@
+// Package secrets encrypts small values, such as TOTP secrets, before they
+// are stored in the database.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+var ErrMalformedCiphertext = errors.New("malformed ciphertext")
+
+// Box seals values with AES-256-GCM. Sealed values are base64 encoded with
+// the nonce prepended.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox derives a 256-bit key from the given secret.
+func NewBox(secret []byte) (*Box, error) {
+	key := sha256.Sum256(secret)
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+func (b *Box) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (b *Box) Open(ciphertext string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < b.aead.NonceSize() {
+		return "", ErrMalformedCiphertext
+	}
+	nonce, sealed := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
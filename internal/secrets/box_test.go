@@ -0,0 +1,25 @@
+package secrets_test
+
+import (
+	"clean-arch/internal/secrets"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBox_RoundTrip(t *testing.T) {
+	box, err := secrets.NewBox([]byte("key"))
+	assert.NoError(t, err)
+
+	sealed, err := box.Seal("JBSWY3DPEHPK3PXP")
+	assert.NoError(t, err)
+	assert.NotContains(t, sealed, "JBSWY3DPEHPK3PXP")
+
+	opened, err := box.Open(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", opened)
+
+	other, _ := secrets.NewBox([]byte("other-key"))
+	_, err = other.Open(sealed)
+	assert.Error(t, err)
+}
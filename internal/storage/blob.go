@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore persists binary objects such as profile pictures and returns the
+// URL they can be fetched from.
+type BlobStore interface {
+	Put(key, contentType string, data io.Reader) (string, error)
+	Delete(key string) error
+}
+
+// LocalBlobStore writes blobs below Root on the local filesystem. BaseURL is
+// the prefix under which Root is served over HTTP.
+type LocalBlobStore struct {
+	Root    string
+	BaseURL string
+}
+
+func NewLocalBlobStore(root, baseURL string) *LocalBlobStore {
+	return &LocalBlobStore{
+		Root:    root,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (s *LocalBlobStore) Put(key, contentType string, data io.Reader) (string, error) {
+	fullPath, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see a partial blob.
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		return "", fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	return s.BaseURL + "/" + key, nil
+}
+
+func (s *LocalBlobStore) Delete(key string) error {
+	fullPath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalBlobStore) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if key == "" || clean == "/" || clean[1:] != key {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(key)), nil
+}
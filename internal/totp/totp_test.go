@@ -0,0 +1,57 @@
+package totp_test
+
+import (
+	"clean-arch/internal/totp"
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The SHA1 vectors from RFC 6238 appendix B, truncated to six digits.
+func TestCode_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range vectors {
+		code, err := totp.Code(secret, totp.Step(time.Unix(unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestValidate_AllowsSkew(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	previous, err := totp.Code(secret, totp.Step(now)-1)
+	assert.NoError(t, err)
+
+	step, ok := totp.Validate(secret, previous, now)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(now)-1, step)
+
+	old, err := totp.Code(secret, totp.Step(now)-3)
+	assert.NoError(t, err)
+	_, ok = totp.Validate(secret, old, now)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := totp.URI("The Furnish Store", "johndoe@gmail.com", "JBSWY3DPEHPK3PXP")
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/The%20Furnish%20Store:johndoe@gmail.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=The+Furnish+Store")
+}
@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+// GormPlugin opens a client span for every statement run through a
+// *gorm.DB. Statements only join the caller's trace when the query was
+// built with db.WithContext.
+type GormPlugin struct {
+	// Database names the connection in span attributes, e.g. "primary".
+	Database string
+}
+
+func (p *GormPlugin) Name() string {
+	return "tracing:" + p.Database
+}
+
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	name := p.Name()
+
+	// The callback processors are unexported types, so each operation is
+	// registered by hand.
+	for _, err := range []error{
+		callback.Create().Before("gorm:create").Register(name+":before_create", p.before("create")),
+		callback.Create().After("gorm:create").Register(name+":after_create", after),
+		callback.Query().Before("gorm:query").Register(name+":before_query", p.before("query")),
+		callback.Query().After("gorm:query").Register(name+":after_query", after),
+		callback.Update().Before("gorm:update").Register(name+":before_update", p.before("update")),
+		callback.Update().After("gorm:update").Register(name+":after_update", after),
+		callback.Delete().Before("gorm:delete").Register(name+":before_delete", p.before("delete")),
+		callback.Delete().After("gorm:delete").Register(name+":after_delete", after),
+		callback.Row().Before("gorm:row").Register(name+":before_row", p.before("row")),
+		callback.Row().After("gorm:row").Register(name+":after_row", after),
+		callback.Raw().Before("gorm:raw").Register(name+":before_raw", p.before("raw")),
+		callback.Raw().After("gorm:raw").Register(name+":after_raw", after),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *GormPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		name := "gorm." + operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		ctx, span := Start(db.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemPostgreSQL,
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(db.Statement.Table),
+				attribute.String("db.instance", p.Database),
+			),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func after(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	// The SQL keeps its placeholders, so bound values never reach the span.
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
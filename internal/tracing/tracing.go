@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters accepted by Config.Exporter.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+	ExporterOTLP   = "otlp"
+)
+
+const instrumentationName = "clean-arch"
+
+type Config struct {
+	ServiceName string
+	// Exporter is one of the Exporter constants. With ExporterNone spans are
+	// still created, so trace IDs reach the logs, but nothing is exported.
+	Exporter string
+	// OTLPEndpoint is the host:port of an OTLP/HTTP collector. When empty
+	// the OTEL_EXPORTER_OTLP_* environment variables apply.
+	OTLPEndpoint string
+	OTLPInsecure bool
+	// File receives the spans as JSON with ExporterFile.
+	File string
+	// SampleRatio is the fraction of new traces that are recorded. Traces
+	// started upstream follow the caller's sampling decision.
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The returned function flushes and stops the exporter.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(config.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	}
+
+	var closer io.Closer
+	switch config.Exporter {
+	case "", ExporterNone:
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	case ExporterFile:
+		file, err := os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, errors.New("failed to open trace file: " + err.Error())
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		closer = file
+		options = append(options, sdktrace.WithBatcher(exporter))
+	case ExporterOTLP:
+		var otlpOptions []otlptracehttp.Option
+		if config.OTLPEndpoint != "" {
+			otlpOptions = append(otlpOptions, otlptracehttp.WithEndpoint(config.OTLPEndpoint))
+		}
+		if config.OTLPInsecure {
+			otlpOptions = append(otlpOptions, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, otlpOptions...)
+		if err != nil {
+			return nil, errors.New("failed to create OTLP exporter: " + err.Error())
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", config.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+		return err
+	}, nil
+}
+
+// Start opens a span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End marks the span as failed if *err is set and ends it. It is meant to be
+// deferred with a pointer to a named error result.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
@@ -0,0 +1,118 @@
+package tracing_test
+
+import (
+	"clean-arch/internal/tracing"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// recordSpans installs a tracer provider that keeps every ended span.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := recordSpans(t)
+
+	var handlerSpan trace.SpanContext
+	router := gin.New()
+	router.Use(tracing.Middleware())
+	router.GET("/users/:id", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /users/:id", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Equal(t, span.SpanContext().SpanID(), handlerSpan.SpanID())
+	assert.Equal(t, "Error", span.Status().Code.String())
+}
+
+func TestGormPlugin_TracesQueriesInCallerTrace(t *testing.T) {
+	recorder := recordSpans(t)
+
+	sqlDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer sqlDB.Close()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.Use(&tracing.GormPlugin{Database: "primary"}))
+
+	mock.ExpectQuery("SELECT").WithArgs("admin").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("admin"))
+
+	ctx, parent := tracing.Start(context.Background(), "UserService.Test")
+	var names []string
+	assert.NoError(t, db.WithContext(ctx).Table("roles").Where("name = ?", "admin").Pluck("name", &names).Error)
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	query := spans[0]
+	assert.Equal(t, "gorm.query roles", query.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+
+	var statement string
+	for _, attr := range query.Attributes() {
+		if attr.Key == "db.query.text" {
+			statement = attr.Value.AsString()
+		}
+	}
+	assert.Contains(t, statement, "$1")
+	assert.NotContains(t, statement, "admin'")
+}
+
+func TestSetup_FileExporterWritesSpans(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName: "user-api-test",
+		Exporter:    tracing.ExporterFile,
+		File:        path,
+		SampleRatio: 1,
+	})
+	assert.NoError(t, err)
+
+	_, span := tracing.Start(context.Background(), "UserService.Login")
+	span.End()
+	assert.NoError(t, shutdown(context.Background()))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `"Name":"UserService.Login"`)
+	assert.Contains(t, string(content), "user-api-test")
+}
+
+func TestSetup_RejectsUnknownExporter(t *testing.T) {
+	_, err := tracing.Setup(context.Background(), tracing.Config{Exporter: "zipkin"})
+	assert.Error(t, err)
+}
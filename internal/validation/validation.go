@@ -0,0 +1,405 @@
+// Package validation checks input structs against their validate tags and
+// turns the failures into field errors in the language the client prefers.
+package validation
+
+import (
+	"clean-arch/internal/core/models"
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	es_translations "github.com/go-playground/validator/v10/translations/es"
+	fr_translations "github.com/go-playground/validator/v10/translations/fr"
+)
+
+// DefaultLocale is used when the client accepts none of the supported
+// languages.
+const DefaultLocale = "en"
+
+var (
+	// phonePattern is E.164 with the leading + optional: up to 15 digits,
+	// not starting with 0.
+	phonePattern    = regexp.MustCompile(`^\+?[1-9]\d{7,14}$`)
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// rule is a custom validation tag with its message in every supported
+// language. Messages take the field name as {0}.
+type rule struct {
+	tag      string
+	fn       validator.Func
+	params   func(fe validator.FieldError) []string
+	messages map[string]string
+}
+
+var rules = []rule{
+	{
+		tag: "phone",
+		fn:  func(fl validator.FieldLevel) bool { return phonePattern.MatchString(fl.Field().String()) },
+		messages: map[string]string{
+			"en": "{0} must be a phone number in E.164 format",
+			"es": "{0} debe ser un número de teléfono en formato E.164",
+			"fr": "{0} doit être un numéro de téléphone au format E.164",
+		},
+	},
+	{
+		tag: "username",
+		fn:  func(fl validator.FieldLevel) bool { return usernamePattern.MatchString(fl.Field().String()) },
+		messages: map[string]string{
+			"en": "{0} may only contain letters, digits, '.', '_' and '-'",
+			"es": "{0} solo puede contener letras, dígitos, '.', '_' y '-'",
+			"fr": "{0} ne peut contenir que des lettres, des chiffres, '.', '_' et '-'",
+		},
+	},
+	{
+		// eqfield is overridden to name the other field as clients see it.
+		tag: "eqfield",
+		params: func(fe validator.FieldError) []string {
+			return []string{snakeCase(fe.Param())}
+		},
+		messages: map[string]string{
+			"en": "{0} must match {1}",
+			"es": "{0} debe coincidir con {1}",
+			"fr": "{0} doit correspondre à {1}",
+		},
+	},
+}
+
+// passwordMessages describe each password policy violation. Messages take
+// the field name as {0} and the limit of the rule, if any, as {1}.
+var passwordMessages = map[string]map[string]string{
+	models.PasswordRuleMinLength: {
+		"en": "{0} must be at least {1} characters long",
+		"es": "{0} debe tener al menos {1} caracteres",
+		"fr": "{0} doit contenir au moins {1} caractères",
+	},
+	models.PasswordRuleMaxLength: {
+		"en": "{0} must be at most {1} bytes long",
+		"es": "{0} debe tener como máximo {1} bytes",
+		"fr": "{0} doit contenir au plus {1} octets",
+	},
+	models.PasswordRuleUpper: {
+		"en": "{0} must contain an uppercase letter",
+		"es": "{0} debe contener una letra mayúscula",
+		"fr": "{0} doit contenir une lettre majuscule",
+	},
+	models.PasswordRuleLower: {
+		"en": "{0} must contain a lowercase letter",
+		"es": "{0} debe contener una letra minúscula",
+		"fr": "{0} doit contenir une lettre minuscule",
+	},
+	models.PasswordRuleDigit: {
+		"en": "{0} must contain a digit",
+		"es": "{0} debe contener un dígito",
+		"fr": "{0} doit contenir un chiffre",
+	},
+	models.PasswordRuleSymbol: {
+		"en": "{0} must contain a symbol",
+		"es": "{0} debe contener un símbolo",
+		"fr": "{0} doit contenir un symbole",
+	},
+	models.PasswordRuleIdentity: {
+		"en": "{0} must not contain the user name or email address",
+		"es": "{0} no debe contener el nombre de usuario ni el correo electrónico",
+		"fr": "{0} ne doit contenir ni le nom d'utilisateur ni l'adresse e-mail",
+	},
+	models.PasswordRuleStrength: {
+		"en": "{0} is too easy to guess",
+		"es": "{0} es demasiado fácil de adivinar",
+		"fr": "{0} est trop facile à deviner",
+	},
+	models.PasswordRuleBreached: {
+		"en": "{0} has appeared in a data breach and must not be used",
+		"es": "{0} ha aparecido en una filtración de datos y no debe usarse",
+		"fr": "{0} est apparu dans une fuite de données et ne doit pas être utilisé",
+	},
+}
+
+// Validator validates structs by their validate tags, naming fields by their
+// json or form tag. It implements Gin's binding.StructValidator.
+//
+// The password tag applies the Validator's PasswordPolicy, and each rule a
+// password breaks is reported as a field error of its own.
+type Validator struct {
+	validate    *validator.Validate
+	translators *ut.UniversalTranslator
+	policy      atomic.Pointer[models.PasswordPolicy]
+}
+
+// Default is the Validator used by the package-level functions and by Gin's
+// binding.
+var Default = mustNew()
+
+func New() (*Validator, error) {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(fieldName)
+
+	english := en.New()
+	translators := ut.New(english, english, es.New(), fr.New())
+	defaults := map[string]func(*validator.Validate, ut.Translator) error{
+		"en": en_translations.RegisterDefaultTranslations,
+		"es": es_translations.RegisterDefaultTranslations,
+		"fr": fr_translations.RegisterDefaultTranslations,
+	}
+	for locale, register := range defaults {
+		trans, _ := translators.GetTranslator(locale)
+		if err := register(validate, trans); err != nil {
+			return nil, err
+		}
+	}
+
+	v := &Validator{validate: validate, translators: translators}
+	v.policy.Store(models.DefaultPasswordPolicy())
+	if err := validate.RegisterValidationCtx("password", func(ctx context.Context, fl validator.FieldLevel) bool {
+		return len(v.checkPassword(ctx, fl.Field().String())) == 0
+	}); err != nil {
+		return nil, err
+	}
+	for rule, messages := range passwordMessages {
+		for locale, message := range messages {
+			trans, _ := translators.GetTranslator(locale)
+			if err := trans.Add(passwordKey(rule), message, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, r := range rules {
+		if r.fn != nil {
+			if err := validate.RegisterValidation(r.tag, r.fn); err != nil {
+				return nil, err
+			}
+		}
+		for locale, message := range r.messages {
+			trans, _ := translators.GetTranslator(locale)
+			if err := validate.RegisterTranslation(r.tag, trans, registerMessage(r.tag, message), translateWith(r.params)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return v, nil
+}
+
+func mustNew() *Validator {
+	v, err := New()
+	if err != nil {
+		panic("validation: " + err.Error())
+	}
+	return v
+}
+
+// ValidateStruct validates a struct or a pointer to one and returns the
+// validator's errors as they are; anything else is not validated.
+func (v *Validator) ValidateStruct(obj any) error {
+	return v.validateStruct(context.Background(), obj)
+}
+
+func (v *Validator) validateStruct(ctx context.Context, obj any) error {
+	value := reflect.ValueOf(obj)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	return v.validate.StructCtx(ctx, value.Interface())
+}
+
+func (v *Validator) Engine() any {
+	return v.validate
+}
+
+// PasswordPolicy returns the policy applied by the password tag.
+func (v *Validator) PasswordPolicy() *models.PasswordPolicy {
+	return v.policy.Load()
+}
+
+// SetPasswordPolicy replaces the policy, which is DefaultPasswordPolicy
+// until it is set.
+func (v *Validator) SetPasswordPolicy(policy *models.PasswordPolicy) {
+	v.policy.Store(policy)
+}
+
+// checkPassword applies the policy with the identities in ctx, if any.
+func (v *Validator) checkPassword(ctx context.Context, password string) []models.PasswordViolation {
+	identities, _ := ctx.Value(identitiesKey{}).([]string)
+	return v.PasswordPolicy().Check(password, identities...)
+}
+
+func (v *Validator) passwordErrors(trans ut.Translator, field string, violations []models.PasswordViolation) []models.FieldError {
+	fields := make([]models.FieldError, 0, len(violations))
+	for _, violation := range violations {
+		key := passwordKey(violation.Rule)
+		message, err := trans.T(key, field, violation.Param)
+		if err != nil {
+			message = field + " breaks the " + violation.Rule + " rule"
+		}
+		fields = append(fields, models.FieldError{Field: field, Message: message, Rule: key})
+	}
+	return fields
+}
+
+// Struct validates obj and reports every invalid field at once, with
+// messages in DefaultLocale. Password fields are checked against the
+// identities ctx carries, see WithIdentities.
+func (v *Validator) Struct(ctx context.Context, obj any) error {
+	if err := v.validateStruct(ctx, obj); err != nil {
+		return v.translate(ctx, err, DefaultLocale)
+	}
+	return nil
+}
+
+// Translate turns validator errors into a validation error listing every
+// invalid field, with messages in the best language for acceptLanguage, an
+// Accept-Language header value. Other errors, such as malformed JSON, become
+// models.ErrInvalidInput.
+func (v *Validator) Translate(err error, acceptLanguage string) error {
+	return v.translate(context.Background(), err, acceptLanguage)
+}
+
+func (v *Validator) translate(ctx context.Context, err error, acceptLanguage string) error {
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return models.Wrap(models.CodeValidation, models.ErrInvalidInput.Message, err)
+	}
+
+	trans := v.translator(acceptLanguage)
+	fields := make([]models.FieldError, 0, len(invalid))
+	for _, fe := range invalid {
+		if fe.Tag() == "password" {
+			password, _ := fe.Value().(string)
+			fields = append(fields, v.passwordErrors(trans, fe.Field(), v.checkPassword(ctx, password))...)
+			continue
+		}
+		fields = append(fields, models.FieldError{Field: fe.Field(), Message: fe.Translate(trans), Rule: fe.Tag()})
+	}
+
+	failed := models.ValidationFailed(fields...)
+	failed.Err = err
+	return failed
+}
+
+// translator picks the supported language the client prefers most.
+func (v *Validator) translator(acceptLanguage string) ut.Translator {
+	trans, _ := v.translators.FindTranslator(preferredLocales(acceptLanguage)...)
+	return trans
+}
+
+// Struct validates obj with the Default validator.
+func Struct(ctx context.Context, obj any) error {
+	return Default.Struct(ctx, obj)
+}
+
+// Translate converts err with the Default validator.
+func Translate(err error, acceptLanguage string) error {
+	return Default.Translate(err, acceptLanguage)
+}
+
+type identitiesKey struct{}
+
+// WithIdentities returns a context under which Struct also refuses
+// passwords containing one of identities, when the policy asks for it.
+// Services pass the user name and email of the account.
+func WithIdentities(ctx context.Context, identities ...string) context.Context {
+	return context.WithValue(ctx, identitiesKey{}, identities)
+}
+
+// passwordKey names the translation of a password rule, which is also the
+// rule clients see.
+func passwordKey(rule string) string {
+	return "password_" + rule
+}
+
+// preferredLocales lists the base languages of an Accept-Language header,
+// most preferred first, followed by DefaultLocale.
+func preferredLocales(acceptLanguage string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+	var accepted []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if base != "" && base != "*" && q > 0 {
+			accepted = append(accepted, weighted{base, q})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].q > accepted[j].q })
+
+	locales := make([]string, 0, len(accepted)+1)
+	for _, a := range accepted {
+		locales = append(locales, a.locale)
+	}
+	return append(locales, DefaultLocale)
+}
+
+// fieldName names a field by its json tag, or its form tag for query
+// parameters, falling back to the Go name.
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// snakeCase converts a Go field name such as NewPassword to new_password,
+// the naming of the json tags.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func registerMessage(tag, message string) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		return trans.Add(tag, message, true)
+	}
+}
+
+func translateWith(params func(fe validator.FieldError) []string) validator.TranslationFunc {
+	return func(trans ut.Translator, fe validator.FieldError) string {
+		args := []string{fe.Field()}
+		if params != nil {
+			args = append(args, params(fe)...)
+		}
+		message, err := trans.T(fe.Tag(), args...)
+		if err != nil {
+			return fe.Error()
+		}
+		return message
+	}
+}
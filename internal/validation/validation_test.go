@@ -0,0 +1,182 @@
+package validation_test
+
+import (
+	"clean-arch/internal/core/models"
+	"clean-arch/internal/validation"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validationError(t *testing.T, err error) *models.Error {
+	t.Helper()
+	var domainErr *models.Error
+	if !assert.True(t, errors.As(err, &domainErr)) {
+		t.FailNow()
+	}
+	assert.Equal(t, models.CodeValidation, domainErr.Code)
+	return domainErr
+}
+
+func TestStruct_ReportsEveryInvalidField(t *testing.T) {
+	err := validation.Struct(context.Background(), &models.SignupInput{
+		UserName:    "a b",
+		Email:       "not-an-email",
+		PhoneNumber: "0123",
+		Password:    "short",
+	})
+
+	domainErr := validationError(t, err)
+	assert.Equal(t, "Validation failed", domainErr.Message)
+	assert.Equal(t, []models.FieldError{
+		{Field: "user_name", Message: "user_name may only contain letters, digits, '.', '_' and '-'", Rule: "username"},
+		{Field: "email", Message: "email must be a valid email address", Rule: "email"},
+		{Field: "phone_number", Message: "phone_number must be a phone number in E.164 format", Rule: "phone"},
+		{Field: "password", Message: "password must be at least 8 characters long", Rule: "password_min_length"},
+	}, domainErr.Fields)
+}
+
+func TestStruct_AcceptsValidInput(t *testing.T) {
+	for _, phone := range []string{"9876543210", "+919876543210", "+14155552671"} {
+		err := validation.Struct(context.Background(), models.SignupInput{
+			UserName:    "john.doe_1",
+			Email:       "john@example.com",
+			PhoneNumber: phone,
+			Password:    "a password that is long",
+		})
+		assert.NoError(t, err, phone)
+	}
+}
+
+func TestStruct_PartialUpdateChecksSentFieldsOnly(t *testing.T) {
+	assert.NoError(t, validation.Struct(context.Background(), &models.UpdateProfileInput{}))
+
+	empty := ""
+	domainErr := validationError(t, validation.Struct(context.Background(), &models.UpdateProfileInput{UserName: &empty}))
+	assert.Equal(t, []models.FieldError{
+		{Field: "user_name", Message: "user_name must be at least 3 characters in length", Rule: "min"},
+	}, domainErr.Fields)
+}
+
+func TestStruct_ReenterMustMatch(t *testing.T) {
+	err := validation.Struct(context.Background(), &models.PasswordReset{
+		CurrentPassword: "old password",
+		NewPassword:     "new password",
+		Reenter:         "another password",
+	})
+
+	domainErr := validationError(t, err)
+	assert.Equal(t, []models.FieldError{
+		{Field: "reenter", Message: "reenter must match new_password", Rule: "eqfield"},
+	}, domainErr.Fields)
+}
+
+func TestTranslate_UsesAcceptLanguage(t *testing.T) {
+	input := &models.ForgotPasswordInput{}
+	validationErr := validation.Default.ValidateStruct(input)
+
+	tests := []struct {
+		acceptLanguage string
+		message        string
+	}{
+		{"", "email is a required field"},
+		{"es-ES,es;q=0.9", "email es un campo requerido"},
+		{"de;q=1, fr;q=0.8, en;q=0.5", "email est un champ obligatoire"},
+		{"en;q=0.2, es;q=0.7", "email es un campo requerido"},
+		{"de", "email is a required field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptLanguage, func(t *testing.T) {
+			domainErr := validationError(t, validation.Translate(validationErr, tt.acceptLanguage))
+			assert.Equal(t, []models.FieldError{{Field: "email", Message: tt.message, Rule: "required"}}, domainErr.Fields)
+		})
+	}
+}
+
+func TestTranslate_MalformedInput(t *testing.T) {
+	var input models.LoginInput
+	jsonErr := json.Unmarshal([]byte(`{"email": 1}`), &input)
+
+	err := validation.Translate(jsonErr, "")
+
+	domainErr := validationError(t, err)
+	assert.Equal(t, models.ErrInvalidInput.Message, domainErr.Message)
+	assert.Empty(t, domainErr.Fields)
+	assert.ErrorIs(t, err, jsonErr)
+}
+
+type breachedList map[string]bool
+
+func (l breachedList) Contains(password string) bool {
+	return l[password]
+}
+
+func newPolicyValidator(t *testing.T, policy *models.PasswordPolicy) *validation.Validator {
+	v, err := validation.New()
+	assert.NoError(t, err)
+	v.SetPasswordPolicy(policy)
+	return v
+}
+
+func TestStruct_ReportsEveryPasswordViolation(t *testing.T) {
+	v := newPolicyValidator(t, &models.PasswordPolicy{
+		MinLength:      12,
+		RequireUpper:   true,
+		RequireDigit:   true,
+		RequireSymbol:  true,
+		RejectIdentity: true,
+		MinStrength:    3,
+		Breached:       breachedList{"johndoe": true},
+	})
+	input := &models.SignupInput{UserName: "johndoe", Email: "john@example.com", PhoneNumber: "9876543210", Password: "johndoe"}
+
+	err := v.Struct(validation.WithIdentities(context.Background(), input.UserName, input.Email), input)
+
+	domainErr := validationError(t, err)
+	assert.Equal(t, []models.FieldError{
+		{Field: "password", Message: "password must be at least 12 characters long", Rule: "password_min_length"},
+		{Field: "password", Message: "password must contain an uppercase letter", Rule: "password_upper"},
+		{Field: "password", Message: "password must contain a digit", Rule: "password_digit"},
+		{Field: "password", Message: "password must contain a symbol", Rule: "password_symbol"},
+		{Field: "password", Message: "password must not contain the user name or email address", Rule: "password_identity"},
+		{Field: "password", Message: "password is too easy to guess", Rule: "password_strength"},
+		{Field: "password", Message: "password has appeared in a data breach and must not be used", Rule: "password_breached"},
+	}, domainErr.Fields)
+}
+
+func TestStruct_IdentityNeedsContext(t *testing.T) {
+	v := newPolicyValidator(t, &models.PasswordPolicy{MinLength: 8, RejectIdentity: true})
+	input := &models.PasswordReset{CurrentPassword: "old password", NewPassword: "JohnDoe2024", Reenter: "JohnDoe2024"}
+
+	assert.NoError(t, v.Struct(context.Background(), input))
+
+	domainErr := validationError(t, v.Struct(validation.WithIdentities(context.Background(), "someone", "johndoe@example.com"), input))
+	assert.Equal(t, []models.FieldError{
+		{Field: "new_password", Message: "new_password must not contain the user name or email address", Rule: "password_identity"},
+	}, domainErr.Fields)
+}
+
+func TestStruct_MaxLengthCountsBytes(t *testing.T) {
+	v := newPolicyValidator(t, &models.PasswordPolicy{MinLength: 8, MaxLength: 16})
+	password := "mot de passe éèê"
+	input := &models.PasswordReset{CurrentPassword: "old password", NewPassword: password, Reenter: password}
+
+	domainErr := validationError(t, v.Struct(context.Background(), input))
+	assert.Equal(t, []models.FieldError{
+		{Field: "new_password", Message: "new_password must be at most 16 bytes long", Rule: "password_max_length"},
+	}, domainErr.Fields)
+}
+
+func TestTranslate_LocalizesPasswordViolations(t *testing.T) {
+	v := newPolicyValidator(t, &models.PasswordPolicy{MinLength: 10, RequireDigit: true})
+	validationErr := v.ValidateStruct(&models.ResetPasswordInput{Token: "token", NewPassword: "short", Reenter: "short"})
+
+	domainErr := validationError(t, v.Translate(validationErr, "es"))
+	assert.Equal(t, []models.FieldError{
+		{Field: "new_password", Message: "new_password debe tener al menos 10 caracteres", Rule: "password_min_length"},
+		{Field: "new_password", Message: "new_password debe contener un dígito", Rule: "password_digit"},
+	}, domainErr.Fields)
+}